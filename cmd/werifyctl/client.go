@@ -2,10 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/rpc"
+	"sort"
+	"strings"
 	"time"
 
 	wrpc "github.com/disq/werify/rpc"
@@ -16,7 +19,13 @@ type client struct {
 	server  string
 	timeout time.Duration
 
+	// profilesDir is where the profiles included in operations files are
+	profilesDir string
+
 	conn *rpc.Client
+
+	// worstStatus is the worst Status of all displayed operation results, used as the exit code
+	worstStatus wrpc.Status
 }
 
 func (c *client) connect() error {
@@ -36,10 +45,46 @@ func (c *client) parseCommand(command string, args []string) error {
 	if !ok {
 		return fmt.Errorf("Unknown command %s", command)
 	}
+
+	var plan bool
+	var varsFile string
+	var strategy wrpc.Strategy
+	if command == "operation" {
+		var maxFailure float64
+
+		fs := flag.NewFlagSet(command, flag.ContinueOnError)
+		fs.BoolVar(&plan, "plan", false, "Report what would run where, without running anything")
+		fs.StringVar(&varsFile, "vars", "", "JSON file of values to use in templates as {{.Vars.name}}")
+		fs.IntVar(&strategy.Canary, "canary", 0, "Number of hosts to run on first")
+		fs.IntVar(&strategy.BatchSize, "batch-size", 0, "Number of hosts to run on in each batch after the canary hosts (default all)")
+		fs.Float64Var(&strategy.BatchPercent, "batch-percent", 0, "Batch size as a percentage of all hosts")
+		fs.StringVar(&strategy.Pause, "pause", "", "Duration to wait between batches")
+		fs.Float64Var(&maxFailure, "max-failure-percent", 0, "Abort if more than this percentage of hosts have CRITICAL checks (default no limit)")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		args = fs.Args()
+
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "max-failure-percent" {
+				strategy.MaxFailurePercent = &maxFailure
+			}
+		})
+		if err := strategy.Validate(); err != nil {
+			return err
+		}
+	}
+
 	if cmdCfg.NumArgs != len(args) {
 		return fmt.Errorf("Invalid number of arguments for %s: Expected %d but got %d", command, cmdCfg.NumArgs, len(args))
 	}
 
+	if cmdCfg.RpcMethod != "" && c.conn == nil {
+		if err := c.connect(); err != nil {
+			return err
+		}
+	}
+
 	rpcCmd := wrpc.BuildMethod(cmdCfg.RpcMethod)
 	ci := c.newCommonInput()
 
@@ -68,6 +113,31 @@ func (c *client) parseCommand(command string, args []string) error {
 			fmt.Printf("Could not remove host %s\n", args[0])
 		}
 
+	case "label":
+		eq := strings.Index(args[1], "=")
+		if eq < 1 {
+			return fmt.Errorf("Invalid label, expected key=value: %s", args[1])
+		}
+		key, value := args[1][:eq], args[1][eq+1:]
+
+		out := wrpc.LabelHostOutput{}
+		in := wrpc.LabelHostInput{
+			CommonInput: ci,
+			Endpoint:    wrpc.Endpoint(args[0]),
+			Labels:      map[string]string{key: value},
+		}
+		err := c.conn.Call(rpcCmd, in, &out)
+		if err != nil {
+			return err
+		}
+		if !out.Ok {
+			fmt.Printf("Could not label host %s\n", args[0])
+		} else if value == "" {
+			fmt.Printf("Removed label %s from host %s\n", key, args[0])
+		} else {
+			fmt.Printf("Labeled host %s with %s=%s\n", args[0], key, value)
+		}
+
 	case "refresh":
 		out := wrpc.RefreshOutput{}
 		err := c.conn.Call(rpcCmd, wrpc.RefreshInput{CommonInput: ci}, &out)
@@ -101,31 +171,51 @@ func (c *client) parseCommand(command string, args []string) error {
 		if command == "list" || command == "listactive" {
 			fmt.Printf("Active hosts (%d)\n", len(out.ActiveHosts))
 			for _, e := range out.ActiveHosts {
-				fmt.Println(e)
+				printHost(e, out.Info, out.Labels)
 			}
 		}
 		if command == "list" || command == "listinactive" {
 			fmt.Printf("Inactive hosts (%d)\n", len(out.InactiveHosts))
 			for _, e := range out.InactiveHosts {
-				fmt.Println(e)
+				printHost(e, out.Info, out.Labels)
 			}
 		}
 		fmt.Println("End of list")
 
 	case "operation":
-		b, err := ioutil.ReadFile(args[0])
+		f, err := loadOpsFile(args[0], c.profilesDir)
 		if err != nil {
-			return fmt.Errorf("Reading %s: %s", args[0], err.Error())
+			return err
 		}
 
 		in := wrpc.OperationInput{
 			CommonInput: ci,
 			Forward:     true,
+			Ops:         f.Ops,
+			Strategy:    strategy,
 		}
 
-		err = json.Unmarshal(b, &in.Ops)
-		if err != nil {
-			return fmt.Errorf("Parsing %s: %s", args[0], err.Error())
+		if varsFile != "" {
+			b, err := ioutil.ReadFile(varsFile)
+			if err != nil {
+				return fmt.Errorf("Reading %s: %s", varsFile, err.Error())
+			}
+			if b, err = stripJSONC(b); err != nil {
+				return fmt.Errorf("Parsing %s: %s", varsFile, err.Error())
+			}
+			if err := json.Unmarshal(b, &in.Vars); err != nil {
+				return fmt.Errorf("Parsing %s: %s", varsFile, err.Error())
+			}
+		}
+
+		if plan {
+			out := wrpc.PlanOutput{}
+			err = c.conn.Call(wrpc.BuildMethod(wrpc.PlanOperationRpcCommand), in, &out)
+			if err != nil {
+				return err
+			}
+			displayPlan(out)
+			return nil
 		}
 
 		out := wrpc.OperationOutput{}
@@ -149,6 +239,24 @@ func (c *client) parseCommand(command string, args []string) error {
 		}
 		c.displayOperation(wrpc.OperationOutput(out))
 
+	case "validate":
+		f, err := loadOpsFile(args[0], c.profilesDir)
+		if err != nil {
+			return err
+		}
+
+		problems := 0
+		for _, name := range sortedOpNames(f.Ops) {
+			for _, e := range f.Ops[name].Validate() {
+				fmt.Printf("%s: %s: %s\n", f.fieldPosition(name, e.Field), name, e.Error())
+				problems++
+			}
+		}
+		if problems > 0 {
+			return fmt.Errorf("%d problems found in %s", problems, args[0])
+		}
+		fmt.Printf("%s: %d operations OK\n", args[0], len(f.Ops))
+
 	default:
 		return fmt.Errorf("Unhandled command %s", command)
 	}
@@ -163,17 +271,97 @@ func (c *client) newCommonInput() wrpc.CommonInput {
 	}
 }
 
+// displayPlan prints which operations would run on which hosts
+func displayPlan(p wrpc.PlanOutput) {
+	ids := make([]string, 0, len(p.Hosts))
+	for id := range p.Hosts {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+
+	total := 0
+	for _, id := range ids {
+		ops := p.Hosts[wrpc.ServerIdentifier(id)]
+		names := make([]string, 0, len(ops))
+		for name := range ops {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			po := ops[name]
+			line := fmt.Sprintf("Host:%s Operation:%s Run:%t", id, name, po.Run)
+			if po.Reason != "" {
+				line += " Reason:" + po.Reason
+			}
+			fmt.Println(line)
+			if po.Run {
+				total++
+			}
+		}
+	}
+
+	fmt.Printf("%d operations would run on %d hosts\n", total, len(ids))
+}
+
+// sortedOpNames returns the names of the operations in order
+func sortedOpNames(ops map[string]wrpc.Operation) []string {
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printHost prints the endpoint with its version information (if known) and labels
+func printHost(e wrpc.Endpoint, info map[wrpc.Endpoint]wrpc.HostInfo, labels map[wrpc.Endpoint]map[string]string) {
+	line := fmt.Sprintf("%s (version unknown)", e)
+	if i, ok := info[e]; ok {
+		line = fmt.Sprintf("%s (%s, build %s)", e, i.ProtoVersion, i.BuildVersion)
+		if i.Hostname != "" {
+			line = fmt.Sprintf("%s (%s, build %s, hostname %s)", e, i.ProtoVersion, i.BuildVersion, i.Hostname)
+		}
+	}
+
+	keys := make([]string, 0, len(labels[e]))
+	for k := range labels[e] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		line += fmt.Sprintf(" %s=%s", k, labels[e][k])
+	}
+
+	fmt.Println(line)
+}
+
 func (c *client) displayOperation(o wrpc.OperationOutput) {
 	for id, res := range o.Results {
 		for name, result := range res {
+			status := result.GetStatus()
+			c.worstStatus = wrpc.WorseStatus(c.worstStatus, status)
+
+			line := fmt.Sprintf("Host:%s Operation:%s Status:%s", id, name, status)
+			if result.Attempts > 1 {
+				line += fmt.Sprintf(" Attempts:%d", result.Attempts)
+			}
+			if result.Output != "" {
+				line += " Output:" + result.Output
+			}
 			if result.Err != "" {
-				fmt.Printf("Host:%s Operation:%s Error:%s\n", id, name, result.Err)
-			} else {
-				fmt.Printf("Host:%s Operation:%s Success:%t\n", id, name, result.Success)
+				line += " Error:" + result.Err
 			}
+			if result.Description != "" {
+				line += " Description:" + result.Description
+			}
+			fmt.Println(line)
 		}
 	}
 
+	if o.Aborted {
+		fmt.Printf("Operation aborted: %s\n", o.AbortReason)
+	}
 	if o.EndedAt != nil {
 		fmt.Printf("Operation ended, took %v\n", o.EndedAt.Sub(o.StartedAt))
 	} else {
@@ -0,0 +1,91 @@
+package main
+
+// jsoncError is an error in JSONC-specific syntax, at Offset
+type jsoncError struct {
+	Offset int64
+	msg    string
+}
+
+func (e *jsoncError) Error() string {
+	return e.msg
+}
+
+// stripJSONC turns JSON with comments (// and /* */) and trailing commas into plain JSON. Removed parts are replaced with
+// whitespace, so that offsets in the plain JSON are the same as in the input, for error messages.
+func stripJSONC(in []byte) ([]byte, error) {
+	out := make([]byte, len(in))
+	copy(out, in)
+
+	blank := func(from, to int) {
+		for i := from; i < to; i++ {
+			if out[i] != '\n' && out[i] != '\r' {
+				out[i] = ' '
+			}
+		}
+	}
+
+	// lastComma is the offset of the last comma which isn't followed by anything but whitespace and comments yet
+	lastComma := -1
+
+	// afterValue is set if the last token was a value. Only a comma after a value can be a trailing comma, so that ie. [,] stays invalid.
+	afterValue := false
+
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		switch {
+		case c == '"':
+			lastComma = -1
+			afterValue = true
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			end := i
+			for end < len(out) && out[end] != '\n' {
+				end++
+			}
+			blank(i, end)
+			i = end
+
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := i + 2
+			for end+1 < len(out) && !(out[end] == '*' && out[end+1] == '/') {
+				end++
+			}
+			if end+1 >= len(out) {
+				return nil, &jsoncError{Offset: int64(i), msg: "Unterminated comment"}
+			}
+			blank(i, end+2)
+			i = end + 1
+
+		case c == ',':
+			lastComma = -1
+			if afterValue {
+				lastComma = i
+			}
+			afterValue = false
+
+		case c == '}' || c == ']':
+			if lastComma > -1 {
+				out[lastComma] = ' '
+			}
+			lastComma = -1
+			afterValue = true
+
+		case c == '{' || c == '[' || c == ':':
+			lastComma = -1
+			afterValue = false
+
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+
+		default:
+			lastComma = -1
+			afterValue = true
+		}
+	}
+
+	return out, nil
+}
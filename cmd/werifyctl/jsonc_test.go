@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStripJSONC(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", in: `{"a": [1, 2]}`, want: `{"a": [1, 2]}`},
+		{name: "line comment", in: "{\"a\": 1 // one\n}", want: "{\"a\": 1       \n}"},
+		{name: "block comment", in: `{/* x */"a": 1}`, want: `{       "a": 1}`},
+		{name: "multiline block comment", in: "[1, /* a\r\nb */ 2]", want: "[1,     \r\n     2]"},
+		{name: "comment in string", in: `{"url": "http://a/*b*/"}`, want: `{"url": "http://a/*b*/"}`},
+		{name: "escaped quote in string", in: `["a\"//", 1]`, want: `["a\"//", 1]`},
+		{name: "trailing comma in object", in: `{"a": 1,}`, want: `{"a": 1 }`},
+		{name: "trailing comma in list", in: `[1, "b",]`, want: `[1, "b" ]`},
+		{name: "trailing comma after object", in: `[{"a": 1},]`, want: `[{"a": 1} ]`},
+		{name: "trailing comma before comment", in: "[1, // x\n]", want: "[1      \n]"},
+		{name: "comma in string", in: `["a,"]`, want: `["a,"]`},
+		{name: "unterminated comment", in: `{"a": 1 /* x`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := stripJSONC([]byte(tt.in))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %t", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+		if len(got) != len(tt.in) {
+			t.Errorf("%s: got length %d, want %d", tt.name, len(got), len(tt.in))
+		}
+	}
+}
+
+func TestStripJSONCInvalid(t *testing.T) {
+	// Commas which don't follow a value are left for the JSON parser to report
+	for _, in := range []string{`[,]`, `{,}`, `[1,,]`, `{"a":,}`, `[1,,2]`} {
+		got, err := stripJSONC([]byte(in))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", in, err)
+			continue
+		}
+		if json.Valid(got) {
+			t.Errorf("%s: got valid JSON %q", in, got)
+		}
+	}
+}
@@ -70,6 +70,7 @@ func main() {
 	env := flag.String("env", envParam("WERIFY_ENV", werify.DefaultEnv), "Env tag")
 	flag.StringVar(&connect, "connect", envParam("WERIFY_CONNECT", fmt.Sprintf("localhost:%d", werify.DefaultPort)), "Connect to werifyd")
 	timeout := flag.Duration("timeout", defaultTimeoutClientToServer, "Connect timeout")
+	profilesDir := flag.String("profiles", envParam("WERIFY_PROFILES", ""), "Directory of profiles to include in operations files (default \"profiles\" next to the including file)")
 
 	flag.Usage = printUsageLine
 	flag.Parse()
@@ -93,14 +94,10 @@ func main() {
 	}
 
 	c := &client{
-		env:     *env,
-		server:  connect,
-		timeout: *timeout,
-	}
-
-	err := c.connect()
-	if err != nil {
-		fail(err, nil)
+		env:         *env,
+		server:      connect,
+		timeout:     *timeout,
+		profilesDir: *profilesDir,
 	}
 
 	if flag.Arg(0) == "-" {
@@ -111,6 +108,10 @@ func main() {
 			fail(err, nil)
 		}
 	}
+
+	if c.worstStatus != "" {
+		os.Exit(c.worstStatus.ExitCode())
+	}
 }
 
 func parseArgsFromFile(c *client, f *os.File) {
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	wrpc "github.com/disq/werify/rpc"
+)
+
+// includeDirective is the key in an operations file to include other operations files and profiles
+const includeDirective = "$include"
+
+// opsFile is a parsed operations file with its includes resolved
+type opsFile struct {
+	Ops map[string]wrpc.Operation
+
+	// sources are the files each of the Ops is defined in
+	sources map[string]*opsSource
+}
+
+// opsSource is a single file making up an operations file, which remembers where things are in it for error messages
+type opsSource struct {
+	filename string
+	data     []byte
+
+	// keys are the byte offsets of object keys, by their dotted path ie. "check_name.params.user"
+	keys map[string]int64
+
+	// values are the byte offsets of the values of the keys
+	values map[string]int64
+}
+
+// opsLoader loads operations files and their includes
+type opsLoader struct {
+	// profilesDir is where all profiles are looked up, if set. Else each file looks them up next to itself, see profilesDirOf.
+	profilesDir string
+
+	// stack is the chain of files being loaded, to detect include cycles
+	stack []string
+}
+
+// loadOpsFile reads and parses the operations file and its includes. Profiles are looked up in profilesDir, or in the "profiles" directory next to the including file if it's empty.
+func loadOpsFile(filename, profilesDir string) (*opsFile, error) {
+	l := &opsLoader{profilesDir: profilesDir}
+	ops, sources, err := l.load(filename, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &opsFile{
+		Ops:     ops,
+		sources: sources,
+	}, nil
+}
+
+// load reads the file and its includes, returning the resulting operations and the files they are defined in.
+// Included operations are overridden by the ones included after them, and by the ones in the file itself.
+// isProfile is set if the file was included as a profile.
+func (l *opsLoader) load(filename string, isProfile bool) (map[string]wrpc.Operation, map[string]*opsSource, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, f := range l.stack {
+		if f == abs {
+			return nil, nil, fmt.Errorf("Include cycle: %s", strings.Join(append(l.stack[i:], abs), " -> "))
+		}
+	}
+	l.stack = append(l.stack, abs)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	src, raw, err := readOpsSource(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ops := make(map[string]wrpc.Operation)
+	sources := make(map[string]*opsSource)
+
+	if incRaw, ok := raw[includeDirective]; ok {
+		var includes []string
+		if err := json.Unmarshal(incRaw, &includes); err != nil {
+			return nil, nil, fmt.Errorf("%s: %s should be a list of files and profiles", src.position(src.values[includeDirective]), includeDirective)
+		}
+
+		for _, inc := range includes {
+			path, incIsProfile, err := l.resolveInclude(filename, isProfile, inc)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s", src.position(src.values[includeDirective]), err.Error())
+			}
+
+			incOps, incSources, err := l.load(path, incIsProfile)
+			if err != nil {
+				return nil, nil, err
+			}
+			for k, v := range incOps {
+				ops[k] = v
+				sources[k] = incSources[k]
+			}
+		}
+	}
+
+	for name, r := range raw {
+		if name == includeDirective {
+			continue
+		}
+		if strings.HasPrefix(name, "$") {
+			return nil, nil, fmt.Errorf("%s: Unknown directive %s", src.position(src.keys[name]), name)
+		}
+
+		// null removes an included operation
+		if string(r) == "null" {
+			delete(ops, name)
+			delete(sources, name)
+			continue
+		}
+
+		var op wrpc.Operation
+		if err := json.Unmarshal(r, &op); err != nil {
+			if e, ok := err.(*json.UnmarshalTypeError); ok {
+				return nil, nil, fmt.Errorf("Parsing %s: %s", src.position(src.values[name]+e.Offset), e.Error())
+			}
+			return nil, nil, fmt.Errorf("Parsing %s: %s", src.position(src.values[name]), err.Error())
+		}
+		ops[name] = op
+		sources[name] = src
+	}
+
+	return ops, sources, nil
+}
+
+// resolveInclude returns the path of the include, and if it's a profile. Entries ending in .json or .jsonc are files relative to the including file, others are profile names.
+func (l *opsLoader) resolveInclude(from string, fromProfile bool, inc string) (string, bool, error) {
+	if strings.HasSuffix(inc, ".json") || strings.HasSuffix(inc, ".jsonc") {
+		if filepath.IsAbs(inc) {
+			return inc, false, nil
+		}
+		return filepath.Join(filepath.Dir(from), inc), false, nil
+	}
+
+	if inc == "" || strings.HasPrefix(inc, ".") || strings.ContainsAny(inc, `/\`) {
+		return "", false, fmt.Errorf("Invalid profile name: %q", inc)
+	}
+	return filepath.Join(l.profilesDirOf(from, fromProfile), inc+".json"), true, nil
+}
+
+// profilesDirOf returns where the profiles included by the file are: the profilesDir if set, the directory of the file if it's a profile itself, or else the "profiles" directory next to the file
+func (l *opsLoader) profilesDirOf(filename string, isProfile bool) string {
+	switch {
+	case l.profilesDir != "":
+		return l.profilesDir
+	case isProfile:
+		return filepath.Dir(filename)
+	default:
+		return filepath.Join(filepath.Dir(filename), "profiles")
+	}
+}
+
+// readOpsSource reads and parses a single file, returning the raw values of its keys
+func readOpsSource(filename string) (*opsSource, map[string]json.RawMessage, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Reading %s: %s", filename, err.Error())
+	}
+
+	src := &opsSource{
+		filename: filename,
+		data:     b,
+		keys:     make(map[string]int64),
+		values:   make(map[string]int64),
+	}
+
+	// Comments and trailing commas are blanked out, so the offsets are the same as in the file
+	b, err = stripJSONC(b)
+	if e, ok := err.(*jsoncError); ok {
+		return nil, nil, fmt.Errorf("Parsing %s: %s", src.position(e.Offset), e.Error())
+	}
+	src.data = b
+
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(b, &raw)
+	switch e := err.(type) {
+	case nil:
+	case *json.SyntaxError:
+		return nil, nil, fmt.Errorf("Parsing %s: %s", src.position(e.Offset), e.Error())
+	case *json.UnmarshalTypeError:
+		return nil, nil, fmt.Errorf("Parsing %s: %s", src.position(e.Offset), e.Error())
+	default:
+		return nil, nil, fmt.Errorf("Parsing %s: %s", filename, err.Error())
+	}
+
+	// The file is valid JSON at this point
+	_ = src.walkJSON(json.NewDecoder(bytes.NewReader(b)), "")
+
+	return src, raw, nil
+}
+
+// position returns the file:line:column of the byte offset
+func (s *opsSource) position(offset int64) string {
+	if offset > int64(len(s.data)) {
+		offset = int64(len(s.data))
+	}
+
+	line, col := 1, 1
+	for _, c := range s.data[:offset] {
+		if c == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return fmt.Sprintf("%s:%d:%d", s.filename, line, col)
+}
+
+// skip returns the offset of the first byte after offset which isn't one of chars
+func (s *opsSource) skip(offset int64, chars string) int64 {
+	for offset < int64(len(s.data)) && strings.IndexByte(chars, s.data[offset]) > -1 {
+		offset++
+	}
+	return offset
+}
+
+// walkJSON reads the next value from dec, recording the offsets of all object keys and their values in it
+func (s *opsSource) walkJSON(dec *json.Decoder, path string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		for dec.More() {
+			// InputOffset is right after the previous token, so skip to the start of the key
+			off := s.skip(dec.InputOffset(), " \t\r\n,")
+
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := fmt.Sprint(tok)
+			if path != "" {
+				key = path + "." + key
+			}
+			s.keys[key] = off
+			s.values[key] = s.skip(dec.InputOffset(), " \t\r\n:")
+
+			if err := s.walkJSON(dec, key); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			if err := s.walkJSON(dec, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Closing delimiter
+	_, err = dec.Token()
+	return err
+}
+
+// fieldPosition returns the position of the field (in FieldError format) of the named operation, or of the operation itself if the field isn't in the file
+func (f *opsFile) fieldPosition(name, field string) string {
+	src := f.sources[name]
+	if off, ok := src.keys[name+"."+field]; ok {
+		return src.position(off)
+	}
+	return src.position(src.keys[name])
+}
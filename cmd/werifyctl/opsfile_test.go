@@ -0,0 +1,170 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	wrpc "github.com/disq/werify/rpc"
+)
+
+// writeFiles creates the files, by their path relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadOpsFileIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"profiles/baseline.json": `{
+			"passwd": {"type": "file_exists", "path": "/etc/passwd"},
+			"hosts": {"type": "file_exists", "path": "/etc/hosts"},
+			"motd": {"type": "file_exists", "path": "/etc/motd"}
+		}`,
+		"common/web.json": `{
+			"hosts": {"type": "file_exists", "path": "/etc/hosts.web"},
+			"nginx": {"type": "process", "check": "cmdline=nginx"}
+		}`,
+		"ops.json": `{
+			// Later includes override earlier ones
+			"$include": ["baseline", "common/web.json"],
+			"nginx": {"type": "process", "check": "cmdline=^nginx: master"},
+			"motd": null,
+		}`,
+	})
+
+	f, err := loadOpsFile(filepath.Join(dir, "ops.json"), "")
+	if err != nil {
+		t.Fatalf("loadOpsFile: %s", err.Error())
+	}
+
+	want := map[string]wrpc.Operation{
+		"passwd": {OpType: "file_exists", PathArg: "/etc/passwd"},
+		"hosts":  {OpType: "file_exists", PathArg: "/etc/hosts.web"},
+		"nginx":  {OpType: "process", CheckArg: "cmdline=^nginx: master"},
+	}
+	if !reflect.DeepEqual(f.Ops, want) {
+		t.Errorf("got %+v, want %+v", f.Ops, want)
+	}
+
+	wantSources := map[string]string{
+		"passwd": "profiles/baseline.json",
+		"hosts":  "common/web.json",
+		"nginx":  "ops.json",
+	}
+	for name, file := range wantSources {
+		if got := f.sources[name].filename; got != filepath.Join(dir, file) {
+			t.Errorf("%s: got source %s, want %s", name, got, file)
+		}
+	}
+
+	// Positions are in the file the operation is defined in
+	if got, want := f.fieldPosition("hosts", "path"), filepath.Join(dir, "common/web.json")+":2:37"; got != want {
+		t.Errorf("got position %s, want %s", got, want)
+	}
+	if got, want := f.fieldPosition("nginx", "params.user"), filepath.Join(dir, "ops.json")+":4:4"; got != want {
+		t.Errorf("got position %s, want %s", got, want)
+	}
+}
+
+func TestLoadOpsFileProfilesDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"top/ops.json":               `{"$include": ["../web/web.json"]}`,
+		"web/web.json":               `{"$include": ["baseline"]}`,
+		"web/profiles/baseline.json": `{"$include": ["common"], "a": {"type": "file_exists", "path": "/a"}}`,
+		"web/profiles/common.json":   `{"b": {"type": "file_exists", "path": "/b"}}`,
+		"other/baseline.json":        `{"c": {"type": "file_exists", "path": "/c"}}`,
+	})
+
+	// Profiles are next to the file including them, and profiles include profiles next to themselves
+	f, err := loadOpsFile(filepath.Join(dir, "top/ops.json"), "")
+	if err != nil {
+		t.Fatalf("loadOpsFile: %s", err.Error())
+	}
+	if got, want := sortedOpNames(f.Ops), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// An explicit profiles directory is used for all files
+	f, err = loadOpsFile(filepath.Join(dir, "web/web.json"), filepath.Join(dir, "other"))
+	if err != nil {
+		t.Fatalf("loadOpsFile: %s", err.Error())
+	}
+	if got, want := sortedOpNames(f.Ops), []string{"c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLoadOpsFileJSONCInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"ops.json":             `{"$include": ["common.jsonc"]}`,
+		"common.jsonc":         "{\n  // Comment\n  \"a\": {\"type\": \"file_exists\", \"path\": \"/a\"},\n}",
+		"profiles/common.json": `{"b": {"type": "file_exists", "path": "/b"}}`,
+	})
+
+	f, err := loadOpsFile(filepath.Join(dir, "ops.json"), "")
+	if err != nil {
+		t.Fatalf("loadOpsFile: %s", err.Error())
+	}
+	if got, want := sortedOpNames(f.Ops), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLoadOpsFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"cycle/a.json":      `{"$include": ["b.json"]}`,
+		"cycle/b.json":      `{"$include": ["a.json"]}`,
+		"self.json":         `{"$include": ["self.json"]}`,
+		"syntax.json":       "{\n  \"a\": {\"type\": \"file_exists\",}\n  \"b\": {}\n}",
+		"type.json":         "{\n  \"a\": {\"type\": \"file_exists\", \"retries\": \"2\"}\n}",
+		"directive.json":    "{\n  \"$includes\": []\n}",
+		"includelist.json":  "{\n  \"$include\": \"baseline\"\n}",
+		"profilename.json":  "{\n  \"$include\": [\"../baseline\"]\n}",
+		"missing.json":      `{"$include": ["nothere.json"]}`,
+		"comment.json":      "{\n  \"a\": 1 /* x\n}",
+		"includeerror.json": `{"$include": ["type.json"]}`,
+	})
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"cycle/a.json", "Include cycle: " + filepath.Join(dir, "cycle/a.json") + " -> " + filepath.Join(dir, "cycle/b.json") + " -> " + filepath.Join(dir, "cycle/a.json")},
+		{"self.json", "Include cycle: "},
+		{"syntax.json", "Parsing " + filepath.Join(dir, "syntax.json") + ":3:4: "},
+		{"type.json", "Parsing " + filepath.Join(dir, "type.json") + ":2:46: "},
+		{"directive.json", filepath.Join(dir, "directive.json") + ":2:3: Unknown directive $includes"},
+		{"includelist.json", filepath.Join(dir, "includelist.json") + ":2:15: $include should be a list"},
+		{"profilename.json", filepath.Join(dir, "profilename.json") + ":2:15: Invalid profile name"},
+		{"missing.json", "Reading " + filepath.Join(dir, "nothere.json")},
+		{"comment.json", "Parsing " + filepath.Join(dir, "comment.json") + ":2:10: "},
+		{"includeerror.json", "Parsing " + filepath.Join(dir, "type.json") + ":2:46: "},
+	}
+
+	for _, tt := range tests {
+		_, err := loadOpsFile(filepath.Join(dir, tt.file), "")
+		if err == nil {
+			t.Errorf("%s: expected error", tt.file)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.file, err.Error(), tt.want)
+		}
+	}
+}
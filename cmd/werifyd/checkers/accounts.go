@@ -0,0 +1,114 @@
+package checkers
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PasswdFile and GroupFile are the local account databases, variables so that tests can use the files in testdata
+var (
+	PasswdFile = "/etc/passwd"
+	GroupFile  = "/etc/group"
+)
+
+// UserAccount is a local user account from the passwd file
+type UserAccount struct {
+	Name  string
+	Uid   int
+	Gid   int
+	Home  string
+	Shell string
+}
+
+// Group is a local group from the group file
+type Group struct {
+	Name    string
+	Gid     int
+	Members []string
+}
+
+// LookupUser finds the user in the passwd file, returning nil if not found
+func LookupUser(name string) (*UserAccount, error) {
+	var found *UserAccount
+
+	err := scanColonFile(PasswdFile, 7, func(fields []string) bool {
+		// name:password:uid:gid:gecos:home:shell
+		if fields[0] != name {
+			return true
+		}
+		uid, err1 := strconv.Atoi(fields[2])
+		gid, err2 := strconv.Atoi(fields[3])
+		if err1 != nil || err2 != nil {
+			return true
+		}
+		found = &UserAccount{Name: name, Uid: uid, Gid: gid, Home: fields[5], Shell: fields[6]}
+		return false
+	})
+
+	return found, err
+}
+
+// LookupGroup finds the group in the group file, returning nil if not found
+func LookupGroup(name string) (*Group, error) {
+	var found *Group
+
+	err := scanColonFile(GroupFile, 4, func(fields []string) bool {
+		// name:password:gid:member1,member2
+		if fields[0] != name {
+			return true
+		}
+		gid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return true
+		}
+		found = &Group{Name: name, Gid: gid}
+		if fields[3] != "" {
+			found.Members = strings.Split(fields[3], ",")
+		}
+		return false
+	})
+
+	return found, err
+}
+
+// IsGroupMember checks if the user is a member of the group, either as a listed member or by primary group
+func IsGroupMember(u *UserAccount, g *Group) bool {
+	if u.Gid == g.Gid {
+		return true
+	}
+	for _, m := range g.Members {
+		if m == u.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// scanColonFile calls fn with the fields of each line of a colon-separated file with numFields fields, until fn returns false.
+// Comments and lines with the wrong number of fields (ie. NIS entries) are skipped.
+func scanColonFile(filename string, numFields int, fn func(fields []string) bool) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) != numFields {
+			continue
+		}
+		if !fn(fields) {
+			break
+		}
+	}
+
+	return s.Err()
+}
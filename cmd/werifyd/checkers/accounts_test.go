@@ -0,0 +1,86 @@
+package checkers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLookupUser(t *testing.T) {
+	setFixture(t, &PasswdFile, "passwd")
+
+	tests := []struct {
+		name string
+		want *UserAccount
+	}{
+		{"root", &UserAccount{Name: "root", Uid: 0, Gid: 0, Home: "/root", Shell: "/bin/bash"}},
+		{"www-data", &UserAccount{Name: "www-data", Uid: 33, Gid: 33, Home: "/var/www", Shell: "/usr/sbin/nologin"}},
+		{"alice", &UserAccount{Name: "alice", Uid: 1000, Gid: 1000, Home: "/home/alice", Shell: "/bin/bash"}},
+		{"broken", nil},
+		{"+nisuser", nil},
+		{"nobody", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := LookupUser(tt.name)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLookupGroup(t *testing.T) {
+	setFixture(t, &GroupFile, "group")
+
+	tests := []struct {
+		name string
+		want *Group
+	}{
+		{"adm", &Group{Name: "adm", Gid: 4, Members: []string{"syslog", "alice"}}},
+		{"www-data", &Group{Name: "www-data", Gid: 33}},
+		{"docker", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := LookupGroup(tt.name)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsGroupMember(t *testing.T) {
+	setFixture(t, &PasswdFile, "passwd")
+	setFixture(t, &GroupFile, "group")
+
+	tests := []struct {
+		user, group string
+		want        bool
+	}{
+		{"alice", "adm", true},
+		{"alice", "sudo", true},
+		{"alice", "alice", true}, // Primary group
+		{"www-data", "www-data", true},
+		{"www-data", "adm", false},
+		{"root", "sudo", false},
+	}
+
+	for _, tt := range tests {
+		u, err := LookupUser(tt.user)
+		if err != nil || u == nil {
+			t.Fatalf("%s: %v", tt.user, err)
+		}
+		g, err := LookupGroup(tt.group)
+		if err != nil || g == nil {
+			t.Fatalf("%s: %v", tt.group, err)
+		}
+		if got := IsGroupMember(u, g); got != tt.want {
+			t.Errorf("%s in %s: got %t, want %t", tt.user, tt.group, got, tt.want)
+		}
+	}
+}
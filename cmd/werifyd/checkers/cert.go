@@ -0,0 +1,69 @@
+package checkers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net"
+)
+
+// ReadCertificates parses the PEM encoded certificates (ie. a full chain) in the file
+func ReadCertificates(filename string) ([]*x509.Certificate, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("No certificates found")
+	}
+	return certs, nil
+}
+
+// FetchCertificates does a TLS handshake with the endpoint (host:port) and returns the presented certificate chain.
+// The chain is not verified. If serverName is empty, the host part of the endpoint is used for SNI.
+func FetchCertificates(ctx context.Context, endpoint, serverName string) ([]*x509.Certificate, error) {
+	if serverName == "" {
+		host, _, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		serverName = host
+	}
+
+	d := &tls.Dialer{Config: &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	}}
+	conn, err := d.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("No certificates presented")
+	}
+	return certs, nil
+}
@@ -0,0 +1,54 @@
+package checkers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// CommandResult is the result of a command run
+type CommandResult struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// RunCommand runs the command without a shell, in dir (if not empty) and with the env vars (KEY=value) added to our environment.
+// The command is killed when ctx is done.
+func RunCommand(ctx context.Context, argv []string, dir string, env []string) (*CommandResult, error) {
+	if len(argv) == 0 || argv[0] == "" {
+		return nil, errors.New("Command is empty")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, errors.New("Command timed out")
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	res := &CommandResult{}
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return nil, err
+		}
+		res.ExitCode = exitErr.ExitCode()
+	}
+
+	res.Stdout = stdout.String()
+	res.Stderr = stderr.String()
+	return res, nil
+}
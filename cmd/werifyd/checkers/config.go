@@ -0,0 +1,134 @@
+package checkers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadConfigValue reads a line-by-line key=value, "key value" or INI style config file and returns the value of the key.
+// Keys are matched case-sensitively, except keys in INI sections, which are referred to as section.key and are matched case-insensitively.
+// Lines starting with # or ; are comments, as is anything after an unquoted # or ; following whitespace in a value.
+// If the key is repeated, the first or last occurrence is returned depending on useLast.
+func ReadConfigValue(ctx context.Context, filename, key string, useLast bool) (value string, found bool, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	var section string
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if err := ctx.Err(); err != nil {
+			return "", false, err
+		}
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		idx := strings.IndexAny(line, "=: \t")
+		if idx < 0 {
+			idx = len(line)
+		}
+		k := line[:idx]
+		if section != "" {
+			if !strings.EqualFold(section+"."+k, key) {
+				continue
+			}
+		} else if k != key {
+			continue
+		}
+
+		v := strings.TrimLeft(line[idx:], " \t")
+		if v != "" && (v[0] == '=' || v[0] == ':') {
+			v = strings.TrimLeft(v[1:], " \t")
+		}
+
+		value, found = configValue(v), true
+		if !useLast {
+			break
+		}
+	}
+
+	return value, found, s.Err()
+}
+
+// configValue returns the value without quotes, or without a trailing comment if it's not quoted
+func configValue(v string) string {
+	if len(v) > 1 && (v[0] == '"' || v[0] == '\'') {
+		// Only if the quotes are around the whole value, apart from a comment
+		if end := strings.IndexByte(v[1:], v[0]); end > -1 {
+			if rest := strings.TrimLeft(v[end+2:], " \t"); rest == "" || rest[0] == '#' || rest[0] == ';' {
+				return v[1 : end+1]
+			}
+		}
+	}
+
+	for i := 1; i < len(v); i++ {
+		if (v[i] == '#' || v[i] == ';') && (v[i-1] == ' ' || v[i-1] == '\t') {
+			return strings.TrimRight(v[:i], " \t")
+		}
+	}
+	return v
+}
+
+// ReadJSONValue reads a JSON file and returns the value at the path expression, ie. servers[0].host or servers.0.host.
+// Strings are returned as-is, other values are returned JSON-encoded.
+func ReadJSONValue(filename, path string) (value string, found bool, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	var doc interface{}
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return "", false, err
+	}
+
+	cur := doc
+	for _, elem := range strings.Split(strings.Replace(strings.Replace(path, "[", ".", -1), "]", "", -1), ".") {
+		if elem == "" {
+			continue
+		}
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			if cur, found = node[elem]; !found {
+				return "", false, nil
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(elem)
+			if err != nil {
+				return "", false, fmt.Errorf("Invalid array index %s", elem)
+			}
+			if i < 0 || i >= len(node) {
+				return "", false, nil
+			}
+			cur = node[i]
+		default:
+			return "", false, nil
+		}
+	}
+
+	if str, ok := cur.(string); ok {
+		return str, true, nil
+	}
+	b, err := json.Marshal(cur)
+	if err != nil {
+		return "", false, err
+	}
+	return string(b), true, nil
+}
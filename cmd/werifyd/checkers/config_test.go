@@ -0,0 +1,77 @@
+package checkers
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadConfigValue(t *testing.T) {
+	tests := []struct {
+		file      string
+		key       string
+		useLast   bool
+		want      string
+		wantFound bool
+	}{
+		{"sshd_config", "PermitRootLogin", false, "no", true},
+		{"sshd_config", "PermitRootLogin", true, "yes", true},
+		{"sshd_config", "permitrootlogin", false, "", false},
+		{"sshd_config", "Port", false, "22", true},
+		{"sshd_config", "PasswordAuthentication", false, "no", true},
+		{"sshd_config", "Banner", false, "/etc/issue # not a comment", true},
+		{"sshd_config", "X11Forwarding", false, "", false},
+		{"app.ini", "name", false, "app", true},
+		{"app.ini", "NAME", false, "other", true},
+		{"app.ini", "color", false, "#fff", true},
+		{"app.ini", "server.port", false, "8080", true},
+		{"app.ini", "Server.Port", false, "8080", true},
+		{"app.ini", "server.host", false, "localhost", true},
+		{"app.ini", "client.port", false, "9090", true},
+		{"app.ini", "client.quoted", false, "a ; b", true},
+		{"app.ini", "port", false, "", false},
+	}
+
+	for _, tt := range tests {
+		got, found, err := ReadConfigValue(context.Background(), filepath.Join("testdata", tt.file), tt.key, tt.useLast)
+		if err != nil {
+			t.Fatalf("%s %s: %s", tt.file, tt.key, err)
+		}
+		if got != tt.want || found != tt.wantFound {
+			t.Errorf("%s %s (last=%t): got %q %t, want %q %t", tt.file, tt.key, tt.useLast, got, found, tt.want, tt.wantFound)
+		}
+	}
+}
+
+func TestReadJSONValue(t *testing.T) {
+	tests := []struct {
+		path      string
+		want      string
+		wantFound bool
+		wantErr   bool
+	}{
+		{path: "name", want: "app", wantFound: true},
+		{path: "server.port", want: "8080", wantFound: true},
+		{path: "server.tls", want: "true", wantFound: true},
+		{path: "server.proxy", want: "null", wantFound: true},
+		{path: "server", want: `{"port":8080,"proxy":null,"tls":true}`, wantFound: true},
+		{path: "servers[1].host", want: "b.example.com", wantFound: true},
+		{path: "servers.0.host", want: "a.example.com", wantFound: true},
+		{path: "tags", want: `["x","y"]`, wantFound: true},
+		{path: "servers[2].host"},
+		{path: "server.missing"},
+		{path: "name.nested"},
+		{path: "servers[x]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, found, err := ReadJSONValue(filepath.Join("testdata", "app.json"), tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %t", tt.path, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want || found != tt.wantFound {
+			t.Errorf("%s: got %q %t, want %q %t", tt.path, got, found, tt.want, tt.wantFound)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package checkers
+
+// DiskStats is the filesystem usage of a mount path
+type DiskStats struct {
+	TotalBytes uint64
+	FreeBytes  uint64
+
+	// AvailBytes is the free space available to unprivileged users
+	AvailBytes uint64
+
+	TotalInodes uint64
+	FreeInodes  uint64
+}
+
+// UsedPercent returns the used space percentage, as seen by unprivileged users (like df does)
+func (d *DiskStats) UsedPercent() float64 {
+	used := d.TotalBytes - d.FreeBytes
+	if used+d.AvailBytes == 0 {
+		return 0
+	}
+	return float64(used) / float64(used+d.AvailBytes) * 100
+}
+
+// UsedInodesPercent returns the used inodes percentage
+func (d *DiskStats) UsedInodesPercent() float64 {
+	if d.TotalInodes == 0 {
+		return 0
+	}
+	return float64(d.TotalInodes-d.FreeInodes) / float64(d.TotalInodes) * 100
+}
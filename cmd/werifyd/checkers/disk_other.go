@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+
+package checkers
+
+import "errors"
+
+// DiskUsage is not supported on this platform
+func DiskUsage(path string) (*DiskStats, error) {
+	return nil, errors.New("Disk usage check is not supported on this platform")
+}
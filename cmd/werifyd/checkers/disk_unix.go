@@ -0,0 +1,23 @@
+//go:build linux || darwin || freebsd
+
+package checkers
+
+import "syscall"
+
+// DiskUsage returns the filesystem usage of the given path
+func DiskUsage(path string) (*DiskStats, error) {
+	var st syscall.Statfs_t
+	err := syscall.Statfs(path, &st)
+	if err != nil {
+		return nil, err
+	}
+
+	bsize := uint64(st.Bsize)
+	return &DiskStats{
+		TotalBytes:  uint64(st.Blocks) * bsize,
+		FreeBytes:   uint64(st.Bfree) * bsize,
+		AvailBytes:  uint64(st.Bavail) * bsize,
+		TotalInodes: uint64(st.Files),
+		FreeInodes:  uint64(st.Ffree),
+	}, nil
+}
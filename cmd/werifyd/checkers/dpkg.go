@@ -0,0 +1,147 @@
+package checkers
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+)
+
+// DpkgStatusFile is the dpkg database, tests point it to testdata
+var DpkgStatusFile = "/var/lib/dpkg/status"
+
+// InstalledPackageVersions returns the versions of the package installed according to the dpkg database.
+// There can be multiple versions for different architectures. Held packages are included. Returns nil if the package is not installed.
+func InstalledPackageVersions(ctx context.Context, name string) ([]string, error) {
+	f, err := os.Open(DpkgStatusFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var versions []string
+	var pkg, status, version string
+
+	endParagraph := func() {
+		// Status is "want flag status", and the wanted state is "hold" for held packages
+		if f := strings.Fields(status); pkg == name && len(f) == 3 && f[2] == "installed" && version != "" {
+			versions = append(versions, version)
+		}
+		pkg, status, version = "", "", ""
+	}
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			endParagraph()
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			// Continuation line
+			continue
+		}
+
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			continue
+		}
+		value := strings.TrimSpace(line[idx+1:])
+		switch line[:idx] {
+		case "Package":
+			pkg = value
+		case "Status":
+			status = value
+		case "Version":
+			version = value
+		}
+	}
+	endParagraph()
+
+	return versions, s.Err()
+}
+
+// CompareDebianVersions compares two Debian package versions ([epoch:]upstream[-revision]) using the dpkg algorithm.
+// Returns -1 if a < b, 0 if a == b, and 1 if a > b.
+func CompareDebianVersions(a, b string) int {
+	aEpoch, aUpstream, aRevision := splitDebianVersion(a)
+	bEpoch, bUpstream, bRevision := splitDebianVersion(b)
+
+	if c := compareDebianPart(aEpoch, bEpoch); c != 0 {
+		return c
+	}
+	if c := compareDebianPart(aUpstream, bUpstream); c != 0 {
+		return c
+	}
+	return compareDebianPart(aRevision, bRevision)
+}
+
+func splitDebianVersion(v string) (epoch, upstream, revision string) {
+	epoch = "0"
+	if idx := strings.Index(v, ":"); idx > -1 {
+		epoch, v = v[:idx], v[idx+1:]
+	}
+	if idx := strings.LastIndex(v, "-"); idx > -1 {
+		v, revision = v[:idx], v[idx+1:]
+	}
+	return epoch, v, revision
+}
+
+// compareDebianPart compares alternating non-digit and digit parts. In non-digit parts, ~ sorts before everything, even the end of the part, and letters sort before non-letters.
+func compareDebianPart(a, b string) int {
+	for a != "" || b != "" {
+		// Non-digit prefix
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			ac, bc := debianCharOrder(a), debianCharOrder(b)
+			if ac != bc {
+				if ac < bc {
+					return -1
+				}
+				return 1
+			}
+			a, b = a[1:], b[1:]
+		}
+
+		// Digit prefix
+		an, bn := 0, 0
+		for a != "" && isDigit(a[0]) {
+			an = an*10 + int(a[0]-'0')
+			a = a[1:]
+		}
+		for b != "" && isDigit(b[0]) {
+			bn = bn*10 + int(b[0]-'0')
+			b = b[1:]
+		}
+		if an != bn {
+			if an < bn {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// debianCharOrder returns the sort weight of the first character of s, 0 if s is empty or starts with a digit
+func debianCharOrder(s string) int {
+	if s == "" || isDigit(s[0]) {
+		return 0
+	}
+	c := s[0]
+	switch {
+	case c == '~':
+		return -1
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,64 @@
+package checkers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestInstalledPackageVersions(t *testing.T) {
+	setFixture(t, &DpkgStatusFile, "dpkg/status")
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"openssl", []string{"1.1.1n-0+deb11u4"}}, // Held
+		{"libc6", []string{"2.31-13+deb11u5", "2.31-13+deb11u5"}},
+		{"telnet", nil},
+		{"nginx", nil},
+		{"curl", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := InstalledPackageVersions(context.Background(), tt.name)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompareDebianVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.0-1", "1.0-2", -1},
+		{"1.0", "1.0-0", 0},
+		{"1:1.0", "2.0", 1},
+		{"0:1.0", "1.0", 0},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0~~", "1.0~", -1},
+		{"1.0a", "1.0", 1},
+		{"1.0a", "1.0+", -1},
+		{"1.1.1n-0+deb11u4", "1.1.1n-0+deb11u3", 1},
+		{"1.1.1n-0+deb11u4", "1.1.1w-0+deb11u1", -1},
+		{"2.31-13+deb11u5", "2.31-13", 1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareDebianVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s vs %s: got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := CompareDebianVersions(tt.b, tt.a); got != -tt.want {
+			t.Errorf("%s vs %s: got %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
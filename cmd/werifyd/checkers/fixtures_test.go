@@ -0,0 +1,15 @@
+package checkers
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// setFixture points one of the system file locations (ie. ProcRoot) to a file or directory in testdata, for the duration of the test
+func setFixture(t *testing.T, v *string, name string) {
+	t.Helper()
+
+	orig := *v
+	*v = filepath.Join("testdata", name)
+	t.Cleanup(func() { *v = orig })
+}
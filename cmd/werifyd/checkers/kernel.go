@@ -0,0 +1,63 @@
+package checkers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadSysctl reads the value of a kernel parameter in sysctl notation (ie. net.ipv4.ip_forward) from /proc/sys.
+// Multiple values (ie. net.ipv4.ip_local_port_range) are returned separated by single spaces.
+func ReadSysctl(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") {
+		return "", errors.New("Invalid sysctl key")
+	}
+
+	// In sysctl notation, dots in names (ie. VLAN interfaces) are written as slashes
+	path := strings.Map(func(r rune) rune {
+		switch r {
+		case '.':
+			return '/'
+		case '/':
+			return '.'
+		}
+		return r
+	}, key)
+
+	b, err := ioutil.ReadFile(filepath.Join(ProcRoot, "sys", path))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(strings.Fields(string(b)), " "), nil
+}
+
+// IsKernelModuleLoaded checks if the kernel module is loaded, according to /proc/modules. Built-in modules are not listed there.
+// Kernels without module support (and some containers) don't have /proc/modules, so nothing is loaded.
+func IsKernelModuleLoaded(name string) (bool, error) {
+	b, err := ioutil.ReadFile(filepath.Join(ProcRoot, "modules"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Module names are listed with underscores, but dashes are interchangeable
+	name = strings.Replace(name, "-", "_", -1)
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		// usb_storage 77824 1 uas, Live 0x0000000000000000
+		fields := strings.Fields(s.Text())
+		if len(fields) > 0 && fields[0] == name {
+			return true, nil
+		}
+	}
+
+	return false, s.Err()
+}
@@ -0,0 +1,68 @@
+package checkers
+
+import "testing"
+
+func TestReadSysctl(t *testing.T) {
+	setFixture(t, &ProcRoot, "proc")
+
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "net.ipv4.ip_forward", want: "0"},
+		{key: "net.ipv4.ip_local_port_range", want: "32768 60999"},
+		{key: "net.ipv4.nonexistent", wantErr: true},
+		{key: "net..ipv4", wantErr: true},
+		{key: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ReadSysctl(tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: got error %v, want error %t", tt.key, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestIsKernelModuleLoaded(t *testing.T) {
+	setFixture(t, &ProcRoot, "proc")
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"usb_storage", true},
+		{"usb-storage", true},
+		{"nf_conntrack", true},
+		{"uas", false},
+		{"usb", false},
+	}
+
+	for _, tt := range tests {
+		got, err := IsKernelModuleLoaded(tt.name)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsKernelModuleLoadedWithoutModules(t *testing.T) {
+	// This fixture doesn't have a modules file either
+	setFixture(t, &ProcRoot, "proc-noipv6")
+
+	got, err := IsKernelModuleLoaded("usb_storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got {
+		t.Error("got loaded without /proc/modules")
+	}
+}
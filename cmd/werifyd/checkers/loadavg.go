@@ -0,0 +1,40 @@
+package checkers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadAverage is the system load average from /proc/loadavg
+type LoadAverage struct {
+	Load1  float64
+	Load5  float64
+	Load15 float64
+}
+
+// ReadLoadAverage parses /proc/loadavg
+func ReadLoadAverage() (*LoadAverage, error) {
+	b, err := ioutil.ReadFile(filepath.Join(ProcRoot, "loadavg"))
+	if err != nil {
+		return nil, err
+	}
+
+	// 0.20 0.18 0.12 1/80 11206
+	fields := strings.Fields(string(b))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("Invalid loadavg: %s", b)
+	}
+
+	var vals [3]float64
+	for i := range vals {
+		vals[i], err = strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &LoadAverage{Load1: vals[0], Load5: vals[1], Load15: vals[2]}, nil
+}
@@ -0,0 +1,17 @@
+package checkers
+
+import "testing"
+
+func TestReadLoadAverage(t *testing.T) {
+	setFixture(t, &ProcRoot, "proc")
+
+	l, err := ReadLoadAverage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := LoadAverage{Load1: 0.20, Load5: 0.18, Load15: 0.12}
+	if *l != want {
+		t.Errorf("got %+v, want %+v", *l, want)
+	}
+}
@@ -0,0 +1,83 @@
+package checkers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MemInfo is the memory usage from /proc/meminfo, in bytes
+type MemInfo struct {
+	TotalBytes     uint64
+	AvailableBytes uint64
+	SwapTotalBytes uint64
+	SwapFreeBytes  uint64
+}
+
+// AvailablePercent returns the percentage of memory available for starting new applications
+func (m *MemInfo) AvailablePercent() float64 {
+	if m.TotalBytes == 0 {
+		return 0
+	}
+	return float64(m.AvailableBytes) / float64(m.TotalBytes) * 100
+}
+
+// SwapUsedPercent returns the percentage of used swap, 0 if there's no swap
+func (m *MemInfo) SwapUsedPercent() float64 {
+	if m.SwapTotalBytes == 0 {
+		return 0
+	}
+	return float64(m.SwapTotalBytes-m.SwapFreeBytes) / float64(m.SwapTotalBytes) * 100
+}
+
+// ReadMemInfo parses /proc/meminfo
+func ReadMemInfo() (*MemInfo, error) {
+	b, err := ioutil.ReadFile(filepath.Join(ProcRoot, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]uint64)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		// MemTotal:       16307796 kB
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 2 && fields[2] == "kB" {
+			v *= 1024
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = v
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, ok := values["MemTotal"]; !ok {
+		return nil, errors.New("MemTotal not found in meminfo")
+	}
+
+	m := &MemInfo{
+		TotalBytes:     values["MemTotal"],
+		SwapTotalBytes: values["SwapTotal"],
+		SwapFreeBytes:  values["SwapFree"],
+	}
+
+	if v, ok := values["MemAvailable"]; ok {
+		m.AvailableBytes = v
+	} else {
+		// Kernels older than 3.14, estimate
+		m.AvailableBytes = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+
+	return m, nil
+}
@@ -0,0 +1,40 @@
+package checkers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReadMemInfo(t *testing.T) {
+	setFixture(t, &ProcRoot, "proc")
+
+	m, err := ReadMemInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := MemInfo{
+		TotalBytes:     16307796 * 1024,
+		AvailableBytes: 8153898 * 1024,
+		SwapTotalBytes: 2097148 * 1024,
+		SwapFreeBytes:  1572861 * 1024,
+	}
+	if *m != want {
+		t.Errorf("got %+v, want %+v", *m, want)
+	}
+
+	if p := m.AvailablePercent(); math.Abs(p-50) > 0.01 {
+		t.Errorf("AvailablePercent: got %g, want 50", p)
+	}
+	if p := m.SwapUsedPercent(); math.Abs(p-25) > 0.01 {
+		t.Errorf("SwapUsedPercent: got %g, want 25", p)
+	}
+}
+
+func TestReadMemInfoMissing(t *testing.T) {
+	setFixture(t, &ProcRoot, "nonexistent")
+
+	if _, err := ReadMemInfo(); err == nil {
+		t.Error("expected error")
+	}
+}
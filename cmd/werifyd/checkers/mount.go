@@ -0,0 +1,80 @@
+package checkers
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Mount is a mounted filesystem entry
+type Mount struct {
+	Source     string
+	MountPoint string
+	FSType     string
+	Options    []string
+}
+
+// HasOption checks if the mount has the given option
+func (m *Mount) HasOption(opt string) bool {
+	for _, o := range m.Options {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// FindMount returns the filesystem mounted on the mount point from /proc/self/mounts, or nil if nothing is mounted there.
+// If there are multiple filesystems mounted on the same mount point, the last (visible) one is returned.
+func FindMount(mountPoint string) (*Mount, error) {
+	b, err := ioutil.ReadFile(filepath.Join(ProcRoot, "self", "mounts"))
+	if err != nil {
+		return nil, err
+	}
+
+	mountPoint = filepath.Clean(mountPoint)
+
+	var found *Mount
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		// /dev/sda1 / ext4 rw,relatime 0 0
+		fields := strings.Fields(s.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		if unescapeMountField(fields[1]) != mountPoint {
+			continue
+		}
+		found = &Mount{
+			Source:     unescapeMountField(fields[0]),
+			MountPoint: mountPoint,
+			FSType:     fields[2],
+			Options:    strings.Split(fields[3], ","),
+		}
+	}
+
+	return found, s.Err()
+}
+
+// unescapeMountField decodes the octal escapes (ie. \040 for space) in the mounts file
+func unescapeMountField(f string) string {
+	if !strings.Contains(f, `\`) {
+		return f
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(f); i++ {
+		if f[i] == '\\' && i+4 <= len(f) {
+			if v, err := strconv.ParseUint(f[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(f[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,44 @@
+package checkers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindMount(t *testing.T) {
+	setFixture(t, &ProcRoot, "proc")
+
+	tests := []struct {
+		mountPoint string
+		want       *Mount
+	}{
+		{"/", &Mount{Source: "/dev/sda1", MountPoint: "/", FSType: "ext4", Options: []string{"rw", "relatime", "errors=remount-ro"}}},
+		{"/proc/", &Mount{Source: "proc", MountPoint: "/proc", FSType: "proc", Options: []string{"rw", "nosuid", "nodev", "noexec", "relatime"}}},
+
+		// Mounted twice, the last one is visible
+		{"/tmp", &Mount{Source: "tmpfs", MountPoint: "/tmp", FSType: "tmpfs", Options: []string{"rw", "nosuid", "nodev", "noexec"}}},
+
+		{"/mnt/my disk", &Mount{Source: "/dev/sdb1", MountPoint: "/mnt/my disk", FSType: "ext4", Options: []string{"ro", "relatime"}}},
+		{"/mnt", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := FindMount(tt.mountPoint)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.mountPoint, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.mountPoint, got, tt.want)
+		}
+	}
+}
+
+func TestMountHasOption(t *testing.T) {
+	m := Mount{Options: []string{"rw", "nosuid"}}
+	if !m.HasOption("nosuid") {
+		t.Error("expected nosuid")
+	}
+	if m.HasOption("noexec") {
+		t.Error("unexpected noexec")
+	}
+}
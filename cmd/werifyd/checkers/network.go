@@ -0,0 +1,138 @@
+package checkers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SysRoot is where the sys filesystem is mounted, like ProcRoot
+var SysRoot = "/sys"
+
+// rtfUp is the RTF_UP route flag
+const rtfUp = 0x1
+
+// Route is an entry in the kernel routing table
+type Route struct {
+	Iface       string
+	Destination *net.IPNet
+	Gateway     net.IP
+}
+
+// InterfaceOperState returns the operational state of the network interface (ie. up, down, unknown) from /sys/class/net
+func InterfaceOperState(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) {
+		return "", os.ErrNotExist
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(SysRoot, "class", "net", name, "operstate"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// ReadRoutes returns the IPv4 and IPv6 routes which are up, from /proc/net/route and /proc/net/ipv6_route
+func ReadRoutes() ([]Route, error) {
+	routes, err := readIPv4Routes()
+	if err != nil {
+		return nil, err
+	}
+
+	routes6, err := readIPv6Routes()
+	if os.IsNotExist(err) {
+		// IPv6 disabled
+		return routes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return append(routes, routes6...), nil
+}
+
+func readIPv4Routes() ([]Route, error) {
+	b, err := ioutil.ReadFile(filepath.Join(ProcRoot, "net", "route"))
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []Route
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT, addresses in little-endian hex
+		fields := strings.Fields(s.Text())
+		if len(fields) < 8 || fields[0] == "Iface" {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&rtfUp == 0 {
+			continue
+		}
+		dst, err1 := parseHexIPv4(fields[1])
+		gw, err2 := parseHexIPv4(fields[2])
+		mask, err3 := parseHexIPv4(fields[7])
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		routes = append(routes, Route{
+			Iface:       fields[0],
+			Destination: &net.IPNet{IP: dst, Mask: net.IPMask(mask)},
+			Gateway:     gw,
+		})
+	}
+
+	return routes, s.Err()
+}
+
+func readIPv6Routes() ([]Route, error) {
+	b, err := ioutil.ReadFile(filepath.Join(ProcRoot, "net", "ipv6_route"))
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []Route
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		// dest dest_prefixlen src src_prefixlen nexthop metric refcnt use flags iface, addresses in big-endian hex
+		fields := strings.Fields(s.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[8], 16, 32)
+		if err != nil || flags&rtfUp == 0 {
+			continue
+		}
+		dst, err1 := hex.DecodeString(fields[0])
+		plen, err2 := strconv.ParseUint(fields[1], 16, 8)
+		gw, err3 := hex.DecodeString(fields[4])
+		if err1 != nil || err2 != nil || err3 != nil || len(dst) != net.IPv6len || len(gw) != net.IPv6len {
+			continue
+		}
+		routes = append(routes, Route{
+			Iface:       fields[9],
+			Destination: &net.IPNet{IP: net.IP(dst), Mask: net.CIDRMask(int(plen), 128)},
+			Gateway:     net.IP(gw),
+		})
+	}
+
+	return routes, s.Err()
+}
+
+// parseHexIPv4 parses a little-endian hex IPv4 address as in /proc/net/route
+func parseHexIPv4(h string) (net.IP, error) {
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return nil, err
+	}
+	ip := make(net.IP, net.IPv4len)
+	binary.LittleEndian.PutUint32(ip, uint32(v))
+	return ip, nil
+}
@@ -0,0 +1,77 @@
+package checkers
+
+import (
+	"os"
+	"testing"
+)
+
+func TestInterfaceOperState(t *testing.T) {
+	setFixture(t, &SysRoot, "sys")
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "eth0", want: "up"},
+		{name: "docker0", want: "down"},
+		{name: "wlan0", wantErr: true},
+		{name: "../eth0", wantErr: true},
+		{name: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := InterfaceOperState(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: got error %v, want error %t", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil && !os.IsNotExist(err) {
+			t.Errorf("%q: got error %v, want not exist", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadRoutes(t *testing.T) {
+	setFixture(t, &ProcRoot, "proc")
+
+	routes, err := ReadRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Routes which aren't up are skipped
+	want := []struct {
+		iface, dst, gw string
+	}{
+		{"eth0", "0.0.0.0/0", "192.168.0.1"},
+		{"eth0", "192.168.0.0/24", "0.0.0.0"},
+		{"eth0", "fe80::/64", "::"},
+		{"eth0", "::/0", "fe80::1"},
+	}
+
+	if len(routes) != len(want) {
+		t.Fatalf("got %d routes, want %d: %+v", len(routes), len(want), routes)
+	}
+	for i, w := range want {
+		r := routes[i]
+		if r.Iface != w.iface || r.Destination.String() != w.dst || r.Gateway.String() != w.gw {
+			t.Errorf("route %d: got %s %s via %s, want %s %s via %s", i, r.Iface, r.Destination, r.Gateway, w.iface, w.dst, w.gw)
+		}
+	}
+}
+
+func TestReadRoutesWithoutIPv6(t *testing.T) {
+	setFixture(t, &ProcRoot, "proc-noipv6")
+
+	routes, err := ReadRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 {
+		t.Errorf("got %d routes, want 2: %+v", len(routes), routes)
+	}
+}
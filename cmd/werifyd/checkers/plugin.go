@@ -0,0 +1,46 @@
+package checkers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// PluginResult is the result of a Nagios-compatible plugin run
+type PluginResult struct {
+	ExitCode int
+
+	// Output is the first line of output, without the perfdata
+	Output string
+
+	// PerfData is the perfdata part of the first line of output
+	PerfData string
+}
+
+// RunPlugin runs the named plugin executable from pluginDir and parses its output. Only plugins directly in pluginDir can be run.
+func RunPlugin(ctx context.Context, pluginDir, name string, args []string) (*PluginResult, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, errors.New("Invalid plugin name")
+	}
+
+	cr, err := RunCommand(ctx, append([]string{filepath.Join(pluginDir, name)}, args...), "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &PluginResult{ExitCode: cr.ExitCode}
+
+	s := bufio.NewScanner(strings.NewReader(cr.Stdout))
+	if s.Scan() {
+		line := s.Text()
+		if idx := strings.Index(line, "|"); idx > -1 {
+			res.PerfData = strings.TrimSpace(line[idx+1:])
+			line = line[:idx]
+		}
+		res.Output = strings.TrimSpace(line)
+	}
+
+	return res, nil
+}
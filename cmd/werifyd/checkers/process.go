@@ -1,29 +1,59 @@
 package checkers
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 )
 
-const procDir = "/proc"
+// ProcRoot is where the proc filesystem is mounted. Tests set it to a directory in testdata.
+var ProcRoot = "/proc"
 
-// IsProcessRunning checks if the process is running
-func IsProcessRunning(checkBasename, checkWithPath string) (bool, error) {
+// userHZ is the unit of process times in /proc/<pid>/stat, which is 100 on all mainstream Linux architectures
+const userHZ = 100
+
+// ProcessInfo is the information of a running process
+type ProcessInfo struct {
+	Pid int
+
+	// Cmdline is the command line of the process, arguments separated by spaces
+	Cmdline string
+
+	Uid      int
+	RSSBytes uint64
+
+	// NumFDs is the number of open file descriptors, -1 if we don't have the permissions to see them
+	NumFDs int
+
+	StartedAt time.Time
+}
+
+// forEachPid calls fn with each pid in ProcRoot, until fn returns false or ctx is done
+func forEachPid(ctx context.Context, fn func(pid string) bool) error {
 	// Assuming Linux
-	dir, err := os.Open(procDir)
+	dir, err := os.Open(ProcRoot)
 	if err != nil {
-		return false, err
+		return err
 	}
 	defer dir.Close()
 
 	for {
 		pids, err := dir.Readdir(100)
 		if err == io.EOF {
-			break
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 
 		for _, fi := range pids {
@@ -36,35 +66,170 @@ func IsProcessRunning(checkBasename, checkWithPath string) (bool, error) {
 				continue
 			}
 
-			// This file is supposed to be readable by all users
-			cmdlineFile := filepath.Join(procDir, fi.Name(), "cmdline")
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !fn(fi.Name()) {
+				return nil
+			}
+		}
+	}
+}
 
-			cmdline, err := ioutil.ReadFile(cmdlineFile)
-			if err != nil {
-				// Process dead?
-				continue
+// IsProcessRunning checks if the process is running
+func IsProcessRunning(ctx context.Context, checkBasename, checkWithPath string) (bool, error) {
+	found := false
+
+	err := forEachPid(ctx, func(pid string) bool {
+		// This file is supposed to be readable by all users
+		cmdlineFile := filepath.Join(ProcRoot, pid, "cmdline")
+
+		cmdline, err := ioutil.ReadFile(cmdlineFile)
+		if err != nil {
+			// Process dead?
+			return true
+		}
+		idx := bytes.Index(cmdline, []byte{0})
+		if idx < 1 {
+			// No NUL-byte in cmdline... This should not happen
+			return true
+		}
+
+		command := string(cmdline[:idx])
+
+		if checkWithPath != "" {
+			if checkWithPath == command {
+				found = true
+				return false
 			}
-			idx := bytes.Index(cmdline, []byte{0})
-			if idx < 1 {
-				// No NUL-byte in cmdline... This should not happen
-				continue
+		}
+		if checkBasename != "" {
+			processName := filepath.Base(command)
+			if processName == checkBasename {
+				found = true
+				return false
 			}
+		}
+		return true
+	})
+
+	return found, err
+}
+
+// FindProcesses returns the processes with cmdlines matching the regexp. Processes without a cmdline (kernel threads) are skipped.
+func FindProcesses(ctx context.Context, match *regexp.Regexp) ([]ProcessInfo, error) {
+	bootTime, err := readBootTime()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []ProcessInfo
+
+	err = forEachPid(ctx, func(pid string) bool {
+		cmdline, err := ioutil.ReadFile(filepath.Join(ProcRoot, pid, "cmdline"))
+		if err != nil || len(cmdline) == 0 {
+			// Process dead, or kernel thread
+			return true
+		}
+		cmd := strings.TrimSpace(string(bytes.Replace(cmdline, []byte{0}, []byte{' '}, -1)))
+		if !match.MatchString(cmd) {
+			return true
+		}
 
-			command := string(cmdline[:idx])
+		p, err := readProcessInfo(pid, bootTime)
+		if err != nil {
+			// Process dead?
+			return true
+		}
+		p.Cmdline = cmd
+		list = append(list, *p)
+		return true
+	})
+
+	return list, err
+}
+
+// readProcessInfo reads the process details from /proc/<pid>/status, stat and fd
+func readProcessInfo(pid string, bootTime time.Time) (*ProcessInfo, error) {
+	p := &ProcessInfo{NumFDs: -1}
+
+	var err error
+	p.Pid, err = strconv.Atoi(pid)
+	if err != nil {
+		return nil, err
+	}
 
-			if checkWithPath != "" {
-				if checkWithPath == command {
-					return true, nil
-				}
+	status, err := ioutil.ReadFile(filepath.Join(ProcRoot, pid, "status"))
+	if err != nil {
+		return nil, err
+	}
+	s := bufio.NewScanner(bytes.NewReader(status))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "Uid:":
+			// Real, effective, saved set, and filesystem UIDs
+			p.Uid, err = strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, err
 			}
-			if checkBasename != "" {
-				processName := filepath.Base(command)
-				if processName == checkBasename {
-					return true, nil
-				}
+		case "VmRSS:":
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			p.RSSBytes = kb * 1024
+		}
+	}
+
+	stat, err := ioutil.ReadFile(filepath.Join(ProcRoot, pid, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	// The command name is in parentheses and can contain spaces, fields after it start from field 3 (state)
+	idx := bytes.LastIndexByte(stat, ')')
+	if idx < 0 {
+		return nil, fmt.Errorf("Invalid stat for pid %s", pid)
+	}
+	fields := strings.Fields(string(stat[idx+1:]))
+	if len(fields) < 20 {
+		return nil, fmt.Errorf("Invalid stat for pid %s", pid)
+	}
+	// Field 22 is starttime
+	ticks, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	p.StartedAt = bootTime.Add(time.Duration(ticks) * time.Second / userHZ)
+
+	if fds, err := ioutil.ReadDir(filepath.Join(ProcRoot, pid, "fd")); err == nil {
+		p.NumFDs = len(fds)
+	}
+
+	return p, nil
+}
+
+// readBootTime reads the boot time from /proc/stat
+func readBootTime() (time.Time, error) {
+	b, err := ioutil.ReadFile(filepath.Join(ProcRoot, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 && fields[0] == "btime" {
+			sec, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, err
 			}
+			return time.Unix(sec, 0), nil
 		}
 	}
 
-	return false, nil
+	return time.Time{}, errors.New("btime not found in stat")
 }
@@ -0,0 +1,77 @@
+package checkers
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestFindProcesses(t *testing.T) {
+	setFixture(t, &ProcRoot, "proc")
+
+	procs, err := FindProcesses(context.Background(), regexp.MustCompile(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].Pid < procs[j].Pid })
+
+	boot := time.Unix(1577836800, 0)
+	want := []ProcessInfo{
+		{Pid: 100, Cmdline: "nginx: master process /usr/sbin/nginx", Uid: 0, RSSBytes: 10240 * 1024, NumFDs: 3, StartedAt: boot.Add(10 * time.Second)},
+		{Pid: 101, Cmdline: "nginx: worker process", Uid: 33, RSSBytes: 204800 * 1024, NumFDs: 5, StartedAt: boot.Add(20 * time.Second)},
+
+		// The command name in stat contains spaces and parentheses
+		{Pid: 200, Cmdline: "/usr/bin/my app --flag", Uid: 1000, RSSBytes: 2048 * 1024, NumFDs: 2, StartedAt: boot.Add(30 * time.Second)},
+
+		// 300 is a kernel thread without a cmdline
+	}
+
+	if len(procs) != len(want) {
+		t.Fatalf("got %d processes, want %d: %+v", len(procs), len(want), procs)
+	}
+	for i, w := range want {
+		p := procs[i]
+		if p.Pid != w.Pid || p.Cmdline != w.Cmdline || p.Uid != w.Uid || p.RSSBytes != w.RSSBytes || p.NumFDs != w.NumFDs || !p.StartedAt.Equal(w.StartedAt) {
+			t.Errorf("got %+v, want %+v", p, w)
+		}
+	}
+}
+
+func TestFindProcessesMatch(t *testing.T) {
+	setFixture(t, &ProcRoot, "proc")
+
+	procs, err := FindProcesses(context.Background(), regexp.MustCompile("^nginx: master"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(procs) != 1 || procs[0].Pid != 100 {
+		t.Errorf("got %+v, want pid 100", procs)
+	}
+}
+
+func TestIsProcessRunning(t *testing.T) {
+	setFixture(t, &ProcRoot, "proc")
+
+	tests := []struct {
+		basename, withPath string
+		want               bool
+	}{
+		{"my app", "", true},
+		{"", "/usr/bin/my app", true},
+		{"nginx:", "", false},
+		{"kthreadd", "", false},
+		{"", "/usr/bin/missing", false},
+	}
+
+	for _, tt := range tests {
+		got, err := IsProcessRunning(context.Background(), tt.basename, tt.withPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%q %q: got %t, want %t", tt.basename, tt.withPath, got, tt.want)
+		}
+	}
+}
@@ -2,13 +2,14 @@ package checkers
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"os"
 	"strings"
 )
 
 // DoesFileHasWord reads a file line by line and checks if the line contains the word
-func DoesFileHasWord(filename, word string) (bool, error) {
+func DoesFileHasWord(ctx context.Context, filename, word string) (bool, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return false, err
@@ -20,6 +21,9 @@ func DoesFileHasWord(filename, word string) (bool, error) {
 	}
 	s := bufio.NewScanner(f)
 	for s.Scan() {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
 		line := s.Text()
 		if strings.Contains(line, word) {
 			return true, nil
@@ -0,0 +1,964 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os/user"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disq/werify/cmd/werifyd/checkers"
+	wrpc "github.com/disq/werify/rpc"
+)
+
+// commandOutputLimit is the maximum size of command output reported in the result
+const commandOutputLimit = 1024
+
+// certFetchTimeout is the connect and handshake timeout for remote cert_expiry checks
+const certFetchTimeout = 10 * time.Second
+
+// checkFunc runs a single check. Apart from the return values, it can fill in the details in res. It should give up when ctx is done.
+type checkFunc func(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error)
+
+// checkType is a registered check type
+type checkType struct {
+	run checkFunc
+
+	// enabled is an optional function to report if the check type is enabled in the server config
+	enabled func(s *Server) bool
+
+	// enableFlag is the werifyd option which enables the check type, if it's disabled by default
+	enableFlag string
+}
+
+func (c checkType) isEnabled(s *Server) bool {
+	return c.enabled == nil || c.enabled(s)
+}
+
+// checkTypes is the registry of supported check types
+var checkTypes = map[wrpc.OperationType]checkType{
+	"file_exists": {run: func(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+		return checkers.DoesFileExist(op.Path())
+	}},
+	"file_contains": {run: func(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+		return checkers.DoesFileHasWord(ctx, op.Path(), string(op.Check()))
+	}},
+	"process_running": {run: func(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+		return checkers.IsProcessRunning(ctx, string(op.Check()), op.Path())
+	}},
+	"process":           {run: runProcessCheck},
+	"mount":             {run: runMountCheck},
+	"sysctl":            {run: runSysctlCheck},
+	"kernel_module":     {run: runKernelModuleCheck},
+	"user_exists":       {run: runUserExistsCheck},
+	"group_member":      {run: runGroupMemberCheck},
+	"package_installed": {run: runPackageInstalledCheck},
+	"interface":         {run: runInterfaceCheck},
+	"route":             {run: runRouteCheck},
+	"json_value":        {run: runJSONValueCheck},
+	"config_value":      {run: runConfigValueCheck},
+	"cert_expiry":       {run: runCertExpiryCheck},
+	"disk_usage":        {run: runDiskUsageCheck},
+	"memory":            {run: runMemoryCheck},
+	"load_average":      {run: runLoadAverageCheck},
+	"exec_plugin": {
+		run:        runPluginCheck,
+		enabled:    func(s *Server) bool { return s.pluginDir != "" },
+		enableFlag: "-plugins",
+	},
+	"command": {
+		run:        runCommandCheck,
+		enabled:    func(s *Server) bool { return s.allowCommands },
+		enableFlag: "-allow-commands",
+	},
+}
+
+// disabledCheckTypeError returns the error for a check type which is registered, but not enabled in the server config
+func disabledCheckTypeError(opType wrpc.OperationType) error {
+	if f := checkTypes[opType].enableFlag; f != "" {
+		return fmt.Errorf("%s is disabled (start werifyd with %s)", opType, f)
+	}
+	return fmt.Errorf("%s is disabled", opType)
+}
+
+// supportedCheckTypes returns the sorted list of registered and enabled check types
+func (s *Server) supportedCheckTypes() []wrpc.OperationType {
+	list := make([]wrpc.OperationType, 0, len(checkTypes))
+	for k, v := range checkTypes {
+		if v.isEnabled(s) {
+			list = append(list, k)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// disabledCheckTypes returns the sorted list of registered check types which aren't enabled
+func (s *Server) disabledCheckTypes() []wrpc.OperationType {
+	var list []wrpc.OperationType
+	for k, v := range checkTypes {
+		if !v.isEnabled(s) {
+			list = append(list, k)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// runPluginCheck runs a Nagios-compatible plugin from the plugin dir, path being the plugin name and check the arguments
+func runPluginCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	if s.pluginDir == "" {
+		return false, errors.New("Plugins are disabled")
+	}
+
+	args, found, err := op.ListParam("args")
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		if args, err = op.Check().Fields(); err != nil {
+			return false, err
+		}
+	}
+
+	// The plugin is killed when ctx is done, at the timeout of the operation
+	pr, err := checkers.RunPlugin(ctx, s.pluginDir, op.Path(), args)
+	if err != nil {
+		res.Status = wrpc.StatusUnknown
+		return false, err
+	}
+
+	res.Status = wrpc.StatusFromExitCode(pr.ExitCode)
+	res.Output = pr.Output
+	res.PerfData = pr.PerfData
+	return res.Status == wrpc.StatusOK, nil
+}
+
+// runMountCheck checks if there's a filesystem mounted on path, with the filesystem type, source and mount options from check options
+func runMountCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+	if op.Path() == "" {
+		return false, errors.New("Path is empty")
+	}
+
+	m, err := checkers.FindMount(op.Path())
+	if err != nil {
+		return false, err
+	}
+	if m == nil {
+		res.Output = fmt.Sprintf("Nothing mounted on %s", op.Path())
+		return false, nil
+	}
+
+	res.Output = fmt.Sprintf("%s on %s type %s (%s)", m.Source, m.MountPoint, m.FSType, strings.Join(m.Options, ","))
+
+	if v := opts["fstype"]; v != "" && v != m.FSType {
+		return false, nil
+	}
+	if v := opts["source"]; v != "" && v != m.Source {
+		return false, nil
+	}
+	if v := opts["options"]; v != "" {
+		for _, o := range strings.Split(v, ",") {
+			if !m.HasOption(o) {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// runSysctlCheck compares the value of the kernel parameter in path to the equal, min or max check options
+func runSysctlCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+	if opts["equal"] == "" && opts["min"] == "" && opts["max"] == "" {
+		return false, errors.New("At least one of equal, min or max options should be supplied")
+	}
+
+	v, err := checkers.ReadSysctl(op.Path())
+	if err != nil {
+		return false, err
+	}
+	res.Output = fmt.Sprintf("%s = %s", op.Path(), v)
+
+	if eq, ok := opts["equal"]; ok && strings.Join(strings.Fields(eq), " ") != v {
+		return false, nil
+	}
+
+	if opts["min"] == "" && opts["max"] == "" {
+		return true, nil
+	}
+
+	num, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return false, fmt.Errorf("Value is not numeric: %s", v)
+	}
+	for _, k := range []string{"min", "max"} {
+		if opts[k] == "" {
+			continue
+		}
+		limit, err := strconv.ParseFloat(opts[k], 64)
+		if err != nil {
+			return false, fmt.Errorf("Invalid %s: %s", k, err.Error())
+		}
+		if (k == "min" && num < limit) || (k == "max" && num > limit) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// runKernelModuleCheck checks if the kernel module in name option is loaded, or not loaded if the loaded option is false
+func runKernelModuleCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+	if opts["name"] == "" {
+		return false, errors.New("name option should be supplied")
+	}
+
+	expected := true
+	if v, ok := opts["loaded"]; ok {
+		if expected, err = strconv.ParseBool(v); err != nil {
+			return false, fmt.Errorf("Invalid loaded: %s", err.Error())
+		}
+	}
+
+	loaded, err := checkers.IsKernelModuleLoaded(opts["name"])
+	if err != nil {
+		return false, err
+	}
+
+	if loaded {
+		res.Output = fmt.Sprintf("%s is loaded", opts["name"])
+	} else {
+		res.Output = fmt.Sprintf("%s is not loaded", opts["name"])
+	}
+	return loaded == expected, nil
+}
+
+// runUserExistsCheck checks if the local user in name option exists (or not, if the present option is false), with the uid, home and shell options
+func runUserExistsCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+	if opts["name"] == "" {
+		return false, errors.New("name option should be supplied")
+	}
+
+	expected := true
+	if v, ok := opts["present"]; ok {
+		if expected, err = strconv.ParseBool(v); err != nil {
+			return false, fmt.Errorf("Invalid present: %s", err.Error())
+		}
+	}
+
+	u, err := checkers.LookupUser(opts["name"])
+	if err != nil {
+		return false, err
+	}
+	if u == nil {
+		res.Output = fmt.Sprintf("User %s does not exist", opts["name"])
+		return !expected, nil
+	}
+
+	res.Output = fmt.Sprintf("User %s uid=%d gid=%d home=%s shell=%s", u.Name, u.Uid, u.Gid, u.Home, u.Shell)
+	if !expected {
+		return false, nil
+	}
+
+	if v := opts["uid"]; v != "" && v != strconv.Itoa(u.Uid) {
+		return false, nil
+	}
+	if v := opts["home"]; v != "" && v != u.Home {
+		return false, nil
+	}
+	if v := opts["shell"]; v != "" && v != u.Shell {
+		return false, nil
+	}
+	return true, nil
+}
+
+// runGroupMemberCheck checks if the local user in user option is a member of the group in group option (or not, if the member option is false)
+func runGroupMemberCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+	if opts["group"] == "" || opts["user"] == "" {
+		return false, errors.New("group and user options should be supplied")
+	}
+
+	expected := true
+	if v, ok := opts["member"]; ok {
+		if expected, err = strconv.ParseBool(v); err != nil {
+			return false, fmt.Errorf("Invalid member: %s", err.Error())
+		}
+	}
+
+	g, err := checkers.LookupGroup(opts["group"])
+	if err != nil {
+		return false, err
+	}
+	if g == nil {
+		res.Output = fmt.Sprintf("Group %s does not exist", opts["group"])
+		return !expected, nil
+	}
+	u, err := checkers.LookupUser(opts["user"])
+	if err != nil {
+		return false, err
+	}
+	if u == nil {
+		res.Output = fmt.Sprintf("User %s does not exist", opts["user"])
+		return !expected, nil
+	}
+
+	member := checkers.IsGroupMember(u, g)
+	if member {
+		res.Output = fmt.Sprintf("User %s is a member of %s", u.Name, g.Name)
+	} else {
+		res.Output = fmt.Sprintf("User %s is not a member of %s", u.Name, g.Name)
+	}
+	return member == expected, nil
+}
+
+// runPackageInstalledCheck checks if the package in name option is installed, with the version constraint in version option
+func runPackageInstalledCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+	if opts["name"] == "" {
+		return false, errors.New("name option should be supplied")
+	}
+
+	// Constraint is one of =, !=, <, <=, >, >= followed by the version. No operator means =.
+	constraint := strings.TrimSpace(opts["version"])
+	rest := strings.TrimLeft(constraint, "=!<>")
+	operator := constraint[:len(constraint)-len(rest)]
+	wanted := strings.TrimSpace(rest)
+	if operator == "" {
+		operator = "="
+	}
+	accepted, ok := map[string][]int{
+		"=":  {0},
+		"!=": {-1, 1},
+		"<":  {-1},
+		"<=": {-1, 0},
+		">":  {1},
+		">=": {0, 1},
+	}[operator]
+	if !ok || (constraint != "" && wanted == "") {
+		return false, fmt.Errorf("Invalid version constraint: %s", constraint)
+	}
+
+	versions, err := checkers.InstalledPackageVersions(ctx, opts["name"])
+	if err != nil {
+		return false, err
+	}
+	if len(versions) == 0 {
+		res.Output = fmt.Sprintf("%s is not installed", opts["name"])
+		return false, nil
+	}
+	res.Output = fmt.Sprintf("%s %s", opts["name"], strings.Join(versions, " "))
+
+	if wanted == "" {
+		return true, nil
+	}
+
+	// All installed versions (ie. for multiple architectures) should satisfy the constraint
+	for _, v := range versions {
+		c := checkers.CompareDebianVersions(v, wanted)
+		matched := false
+		for _, a := range accepted {
+			matched = matched || a == c
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// runInterfaceCheck checks if the network interface in name option exists, with the up, mtu, ipv4 and ipv6 options
+func runInterfaceCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+	if opts["name"] == "" {
+		return false, errors.New("name option should be supplied")
+	}
+
+	iface, err := net.InterfaceByName(opts["name"])
+	if err != nil {
+		res.Output = err.Error()
+		return false, nil
+	}
+
+	state, err := checkers.InterfaceOperState(iface.Name)
+	if err != nil {
+		return false, err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return false, err
+	}
+	var has4, has6 bool
+	addrList := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		addrList = append(addrList, a.String())
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			has4 = true
+		} else if ipNet.IP.IsGlobalUnicast() {
+			has6 = true
+		}
+	}
+
+	res.Output = fmt.Sprintf("%s state=%s mtu=%d addrs=%s", iface.Name, state, iface.MTU, strings.Join(addrList, ","))
+
+	// Interfaces without operstate support (ie. loopback, tun) report unknown
+	isUp := state == "up" || (state == "unknown" && iface.Flags&net.FlagUp != 0)
+
+	expectations := map[string]bool{"up": isUp, "ipv4": has4, "ipv6": has6}
+	for k, actual := range expectations {
+		v, ok := opts[k]
+		if !ok {
+			continue
+		}
+		expected, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("Invalid %s: %s", k, err.Error())
+		}
+		if expected != actual {
+			return false, nil
+		}
+	}
+
+	if v := opts["mtu"]; v != "" && v != strconv.Itoa(iface.MTU) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// runRouteCheck checks if there's a route to the CIDR (or default route) in to option, optionally through the interface in dev option
+func runRouteCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+
+	to := opts["to"]
+	var target *net.IPNet
+	switch to {
+	case "":
+		return false, errors.New("to option should be supplied")
+	case "default":
+		_, target, _ = net.ParseCIDR("0.0.0.0/0")
+	case "default6":
+		_, target, _ = net.ParseCIDR("::/0")
+	default:
+		if !strings.Contains(to, "/") {
+			if ip := net.ParseIP(to); ip != nil && ip.To4() != nil {
+				to += "/32"
+			} else {
+				to += "/128"
+			}
+		}
+		_, target, err = net.ParseCIDR(to)
+		if err != nil {
+			return false, fmt.Errorf("Invalid to: %s", err.Error())
+		}
+	}
+	targetOnes, targetBits := target.Mask.Size()
+
+	routes, err := checkers.ReadRoutes()
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range routes {
+		ones, bits := r.Destination.Mask.Size()
+		if bits != targetBits || (opts["dev"] != "" && r.Iface != opts["dev"]) {
+			continue
+		}
+
+		// The default route only matches if it's asked for, otherwise the route should cover the target
+		if (targetOnes == 0) != (ones == 0) || ones > targetOnes || !r.Destination.Contains(target.IP) {
+			continue
+		}
+
+		res.Output = fmt.Sprintf("%s via %s dev %s", r.Destination, r.Gateway, r.Iface)
+		return true, nil
+	}
+
+	res.Output = fmt.Sprintf("No route to %s", opts["to"])
+	return false, nil
+}
+
+// runJSONValueCheck compares the value at the key option path expression in the JSON file in path to the equal option
+func runJSONValueCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+	if opts["key"] == "" {
+		return false, errors.New("key option should be supplied")
+	}
+
+	v, found, err := checkers.ReadJSONValue(op.Path(), opts["key"])
+	if err != nil {
+		return false, err
+	}
+	return compareConfigValue(opts, v, found, res), nil
+}
+
+// runConfigValueCheck compares the value of the key option in the config file in path to the equal option
+func runConfigValueCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+	if opts["key"] == "" {
+		return false, errors.New("key option should be supplied")
+	}
+
+	// First is the default, like sshd_config and most programs which ignore repeated keys
+	var useLast bool
+	switch opts["occurrence"] {
+	case "", "first":
+	case "last":
+		useLast = true
+	default:
+		return false, fmt.Errorf("Invalid occurrence: %s", opts["occurrence"])
+	}
+
+	v, found, err := checkers.ReadConfigValue(ctx, op.Path(), opts["key"], useLast)
+	if err != nil {
+		return false, err
+	}
+	return compareConfigValue(opts, v, found, res), nil
+}
+
+// compareConfigValue compares the found value to the equal option. Without the equal option, the key is expected to exist.
+func compareConfigValue(opts map[string]string, v string, found bool, res *wrpc.OperationResult) bool {
+	if !found {
+		res.Output = fmt.Sprintf("%s not found", opts["key"])
+		return false
+	}
+
+	res.Output = fmt.Sprintf("%s = %s", opts["key"], v)
+	expected, ok := opts["equal"]
+	return !ok || expected == v
+}
+
+// runCertExpiryCheck checks the days left until the soonest expiring certificate in the PEM file in path (or presented by the endpoint option) expires, against the thresholds
+func runCertExpiryCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+
+	var certs []*x509.Certificate
+	switch {
+	case opts["endpoint"] != "" && op.Path() != "":
+		return false, errors.New("Only one of path or endpoint option should be supplied")
+	case opts["endpoint"] != "":
+		fetchCtx, cancel := context.WithTimeout(ctx, certFetchTimeout)
+		defer cancel()
+		certs, err = checkers.FetchCertificates(fetchCtx, opts["endpoint"], opts["server_name"])
+	case op.Path() != "":
+		certs, err = checkers.ReadCertificates(op.Path())
+	default:
+		return false, errors.New("One of path or endpoint option should be supplied")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	soonest := certs[0]
+	for _, c := range certs[1:] {
+		if c.NotAfter.Before(soonest.NotAfter) {
+			soonest = c
+		}
+	}
+	days := time.Until(soonest.NotAfter).Hours() / 24
+
+	// Expired certificates are critical, unless a critical threshold is given
+	t := op.Thresholds
+	if t.Critical == nil {
+		zero := 0.0
+		t.Critical = &zero
+	}
+
+	res.Status = t.Evaluate(days, false)
+	res.Output = fmt.Sprintf("subject=%s issuer=%s not_after=%s (%.1f days)", soonest.Subject, soonest.Issuer, soonest.NotAfter.UTC().Format(time.RFC3339), days)
+	res.PerfData = formatPerfData("days", days, t)
+	return res.Status == wrpc.StatusOK, nil
+}
+
+// runCommandCheck runs the command line in path, and checks the exit code and output against the exit, stdout and stderr options
+func runCommandCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	if !s.allowCommands {
+		return false, errors.New("Commands are disabled")
+	}
+
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+
+	var argv []string
+	useShell := false
+	if v, ok := opts["shell"]; ok {
+		if useShell, err = strconv.ParseBool(v); err != nil {
+			return false, fmt.Errorf("Invalid shell: %s", err.Error())
+		}
+	}
+	args, argsFound, err := op.ListParam("args")
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case argsFound && useShell:
+		return false, errors.New("Can't use args with shell")
+	case argsFound:
+		argv = args
+	case useShell:
+		argv = []string{"/bin/sh", "-c", op.Path()}
+	default:
+		if argv, err = wrpc.OperationArgument(op.Path()).Fields(); err != nil {
+			return false, err
+		}
+	}
+
+	expectedExit := 0
+	if v, ok := opts["exit"]; ok {
+		if expectedExit, err = strconv.Atoi(v); err != nil {
+			return false, fmt.Errorf("Invalid exit: %s", err.Error())
+		}
+	}
+
+	// A list in params, or whitespace-separated in the check argument, so that values can contain commas
+	env, envFound, err := op.ListParam("env")
+	if err != nil {
+		return false, err
+	}
+	if !envFound && opts["env"] != "" {
+		if env, err = wrpc.OperationArgument(opts["env"]).Fields(); err != nil {
+			return false, err
+		}
+	}
+	for _, e := range env {
+		if strings.Index(e, "=") < 1 {
+			return false, fmt.Errorf("Invalid env, expected NAME=value: %s", e)
+		}
+	}
+
+	// The command is killed when ctx is done, at the timeout of the operation
+	cr, err := checkers.RunCommand(ctx, argv, opts["dir"], env)
+	if err != nil {
+		return false, err
+	}
+
+	output := strings.TrimSpace(strings.TrimSpace(cr.Stdout) + "\n" + strings.TrimSpace(cr.Stderr))
+	if len(output) > commandOutputLimit {
+		output = output[:commandOutputLimit] + "..."
+	}
+	res.Output = fmt.Sprintf("exit %d", cr.ExitCode)
+	if output != "" {
+		res.Output += ": " + output
+	}
+
+	if cr.ExitCode != expectedExit {
+		return false, nil
+	}
+	if v, ok := opts["stdout"]; ok && !strings.Contains(cr.Stdout, v) {
+		return false, nil
+	}
+	if v, ok := opts["stderr"]; ok && !strings.Contains(cr.Stderr, v) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// runDiskUsageCheck checks the filesystem usage of path, using the metric from check options against the thresholds
+func runDiskUsageCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+	if op.Path() == "" {
+		return false, errors.New("Path is empty")
+	}
+
+	d, err := checkers.DiskUsage(op.Path())
+	if err != nil {
+		return false, err
+	}
+
+	values := map[string]float64{
+		"used_percent":        d.UsedPercent(),
+		"used_inodes_percent": d.UsedInodesPercent(),
+		"free_bytes":          float64(d.AvailBytes),
+		"free_inodes":         float64(d.FreeInodes),
+	}
+
+	metric := opts["metric"]
+	if metric == "" {
+		metric = "used_percent"
+	}
+	value, ok := values[metric]
+	if !ok {
+		return false, fmt.Errorf("Invalid metric: %s", metric)
+	}
+
+	res.Status = op.Thresholds.Evaluate(value, strings.HasPrefix(metric, "used_"))
+	res.Output = fmt.Sprintf("%s: %.2f%% used, %d bytes free, %.2f%% inodes used, %d inodes free", op.Path(), values["used_percent"], d.AvailBytes, values["used_inodes_percent"], d.FreeInodes)
+	res.PerfData = formatPerfData(metric, value, op.Thresholds)
+	return res.Status == wrpc.StatusOK, nil
+}
+
+// runMemoryCheck checks the memory usage, using the metric from check options against the thresholds
+func runMemoryCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+
+	m, err := checkers.ReadMemInfo()
+	if err != nil {
+		return false, err
+	}
+
+	var value float64
+	var higherIsWorse bool
+
+	metric := opts["metric"]
+	switch metric {
+	case "", "available_percent":
+		metric = "available_percent"
+		value = m.AvailablePercent()
+	case "swap_used_percent":
+		value, higherIsWorse = m.SwapUsedPercent(), true
+	default:
+		return false, fmt.Errorf("Invalid metric: %s", metric)
+	}
+
+	res.Status = op.Thresholds.Evaluate(value, higherIsWorse)
+	res.Output = fmt.Sprintf("%.2f%% memory available, %.2f%% swap used", m.AvailablePercent(), m.SwapUsedPercent())
+	res.PerfData = formatPerfData(metric, value, op.Thresholds)
+	return res.Status == wrpc.StatusOK, nil
+}
+
+// runLoadAverageCheck checks the load average of the period from check options against the thresholds
+func runLoadAverageCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+
+	l, err := checkers.ReadLoadAverage()
+	if err != nil {
+		return false, err
+	}
+
+	var value float64
+
+	period := opts["period"]
+	switch period {
+	case "", "1":
+		period = "1"
+		value = l.Load1
+	case "5":
+		value = l.Load5
+	case "15":
+		value = l.Load15
+	default:
+		return false, fmt.Errorf("Invalid period: %s", period)
+	}
+
+	label := "load" + period
+	if opts["per_cpu"] != "" {
+		perCPU, err := strconv.ParseBool(opts["per_cpu"])
+		if err != nil {
+			return false, fmt.Errorf("Invalid per_cpu: %s", err.Error())
+		}
+		if perCPU {
+			value /= float64(runtime.NumCPU())
+			label += "_per_cpu"
+		}
+	}
+
+	res.Status = op.Thresholds.Evaluate(value, true)
+	res.Output = fmt.Sprintf("load average: %.2f, %.2f, %.2f (%d CPUs)", l.Load1, l.Load5, l.Load15, runtime.NumCPU())
+	res.PerfData = formatPerfData(label, value, op.Thresholds)
+	return res.Status == wrpc.StatusOK, nil
+}
+
+// runProcessCheck finds the processes matching the cmdline regexp and user from check options, and checks their count and resource usage
+func runProcessCheck(ctx context.Context, s *Server, op *wrpc.Operation, res *wrpc.OperationResult) (bool, error) {
+	opts, err := op.Options()
+	if err != nil {
+		return false, err
+	}
+	if opts["cmdline"] == "" && opts["user"] == "" {
+		return false, errors.New("At least one of cmdline or user options should be supplied")
+	}
+
+	match, err := regexp.Compile(opts["cmdline"])
+	if err != nil {
+		return false, fmt.Errorf("Invalid cmdline: %s", err.Error())
+	}
+
+	uid := -1
+	if opts["user"] != "" {
+		uid, err = lookupUid(opts["user"])
+		if err != nil {
+			return false, err
+		}
+	}
+
+	minCount, maxCount := 1, -1
+	if v, ok := opts["min"]; ok {
+		if minCount, err = strconv.Atoi(v); err != nil {
+			return false, fmt.Errorf("Invalid min: %s", err.Error())
+		}
+	}
+	if v, ok := opts["max"]; ok {
+		if maxCount, err = strconv.Atoi(v); err != nil {
+			return false, fmt.Errorf("Invalid max: %s", err.Error())
+		}
+	}
+
+	var maxRSS uint64
+	if v, ok := opts["max_rss"]; ok {
+		if maxRSS, err = parseBytes(v); err != nil {
+			return false, fmt.Errorf("Invalid max_rss: %s", err.Error())
+		}
+	}
+	maxFDs := -1
+	if v, ok := opts["max_fds"]; ok {
+		if maxFDs, err = strconv.Atoi(v); err != nil {
+			return false, fmt.Errorf("Invalid max_fds: %s", err.Error())
+		}
+	}
+	var minAge, maxAge time.Duration
+	if v, ok := opts["min_age"]; ok {
+		if minAge, err = time.ParseDuration(v); err != nil {
+			return false, fmt.Errorf("Invalid min_age: %s", err.Error())
+		}
+	}
+	if v, ok := opts["max_age"]; ok {
+		if maxAge, err = time.ParseDuration(v); err != nil {
+			return false, fmt.Errorf("Invalid max_age: %s", err.Error())
+		}
+	}
+
+	procs, err := checkers.FindProcesses(ctx, match)
+	if err != nil {
+		return false, err
+	}
+
+	var pids, problems []string
+	for _, p := range procs {
+		if uid > -1 && p.Uid != uid {
+			continue
+		}
+		pids = append(pids, strconv.Itoa(p.Pid))
+
+		if maxFDs > -1 && p.NumFDs < 0 {
+			return false, fmt.Errorf("Can't read open fds of pid %d", p.Pid)
+		}
+		if maxFDs > -1 && p.NumFDs > maxFDs {
+			problems = append(problems, fmt.Sprintf("pid %d has %d open fds", p.Pid, p.NumFDs))
+		}
+		if maxRSS > 0 && p.RSSBytes > maxRSS {
+			problems = append(problems, fmt.Sprintf("pid %d rss %d bytes", p.Pid, p.RSSBytes))
+		}
+		if age := time.Since(p.StartedAt); (minAge > 0 && age < minAge) || (maxAge > 0 && age > maxAge) {
+			problems = append(problems, fmt.Sprintf("pid %d age %v", p.Pid, age.Truncate(time.Second)))
+		}
+	}
+
+	if len(pids) < minCount {
+		problems = append(problems, fmt.Sprintf("expected at least %d", minCount))
+	}
+	if maxCount > -1 && len(pids) > maxCount {
+		problems = append(problems, fmt.Sprintf("expected at most %d", maxCount))
+	}
+
+	res.Output = fmt.Sprintf("%d matching processes", len(pids))
+	if len(pids) > 0 {
+		res.Output += ", pids " + strings.Join(pids, " ")
+	}
+	if len(problems) > 0 {
+		res.Output += ": " + strings.Join(problems, ", ")
+	}
+	res.PerfData = formatPerfData("count", float64(len(pids)), wrpc.Thresholds{})
+	return len(problems) == 0, nil
+}
+
+// lookupUid returns the uid of the user name, or the uid itself if numeric
+func lookupUid(name string) (int, error) {
+	if uid, err := strconv.Atoi(name); err == nil {
+		return uid, nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// parseBytes parses a byte size with an optional K, M, G or T suffix (powers of 1024)
+func parseBytes(v string) (uint64, error) {
+	mul := uint64(1)
+	if len(v) > 0 {
+		if i := strings.IndexByte("KMGT", v[len(v)-1]); i > -1 {
+			mul = 1 << (10 * uint(i+1))
+			v = v[:len(v)-1]
+		}
+	}
+
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * mul, nil
+}
+
+// formatPerfData formats a value with its thresholds as Nagios perfdata
+func formatPerfData(label string, value float64, t wrpc.Thresholds) string {
+	str := func(f *float64) string {
+		if f == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%s=%s;%s;%s", label, strconv.FormatFloat(value, 'f', -1, 64), str(t.Warning), str(t.Critical))
+}
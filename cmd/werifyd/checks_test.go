@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/disq/werify/cmd/werifyd/checkers"
+	wrpc "github.com/disq/werify/rpc"
+)
+
+// useProcFixture points the checkers to the proc fixture for the duration of the test
+func useProcFixture(t *testing.T) {
+	orig := checkers.ProcRoot
+	checkers.ProcRoot = "checkers/testdata/proc"
+	t.Cleanup(func() { checkers.ProcRoot = orig })
+}
+
+func TestRunProcessCheck(t *testing.T) {
+	useProcFixture(t)
+
+	// The fixture processes started in 2020
+	tests := []struct {
+		check string
+		want  bool
+	}{
+		{"cmdline=^nginx:", true},
+		{"cmdline=^nginx: min=3", false},
+		{"cmdline=^nginx: max=1", false},
+		{"cmdline=nginx user=33", true},
+		{"cmdline=nginx user=33 min=2", false},
+		{"user=1000", true},
+		{"user=1001", false},
+		{"cmdline=^nginx: max_rss=100M", false},
+		{"cmdline=^nginx: max_rss=200M", true},
+		{`cmdline="^nginx: master" max_rss=10M`, true},
+		{`cmdline="^nginx: master" max_rss=9M`, false},
+		{"cmdline=^nginx: max_fds=5", true},
+		{"cmdline=^nginx: max_fds=4", false},
+		{"cmdline=^nginx: min_age=24h", true},
+		{"cmdline=^nginx: max_age=24h", false},
+		{`cmdline="^/usr/bin/my app --flag$"`, true},
+	}
+
+	for _, tt := range tests {
+		op := &wrpc.Operation{OpType: "process", CheckArg: wrpc.OperationArgument(tt.check)}
+		res := &wrpc.OperationResult{}
+		got, err := runProcessCheck(context.Background(), &Server{}, op, res)
+		if err != nil {
+			t.Errorf("%s: %s", tt.check, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %t, want %t (%s)", tt.check, got, tt.want, res.Output)
+		}
+	}
+}
+
+func TestOperationRunnerValidates(t *testing.T) {
+	useProcFixture(t)
+	s := &Server{metrics: newMetrics()}
+
+	// A misspelled option would be ignored by the check
+	op := &wrpc.Operation{OpType: "mount", PathArg: "/", CheckArg: "fstyp=nfs"}
+	res := s.operationRunner(context.Background(), op)
+	if res.Status != wrpc.StatusUnknown || !strings.Contains(res.Err, "fstyp") {
+		t.Errorf("invalid op: got %s (%s), want %s with the field error", res.Status, res.Err, wrpc.StatusUnknown)
+	}
+
+	op = &wrpc.Operation{OpType: "process", Params: map[string]interface{}{"cmdline": "^nginx:"}}
+	res = s.operationRunner(context.Background(), op)
+	if res.Status != wrpc.StatusOK {
+		t.Errorf("valid op: got %s (%s), want %s", res.Status, res.Err, wrpc.StatusOK)
+	}
+}
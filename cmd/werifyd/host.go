@@ -1,12 +1,16 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/rpc"
+	"strings"
 	"time"
 
-	"errors"
+	"github.com/disq/werify"
 	t "github.com/disq/werify/cmd/werifyd/types"
 	wrpc "github.com/disq/werify/rpc"
 )
@@ -22,16 +26,38 @@ func (s *Server) connect(h *t.Host) error {
 	h.Lock()
 	defer h.Unlock()
 
+	return s.dial(h)
+}
+
+// reconnect closes the existing connection to the host (if any) and makes a new one. Host should be locked.
+func (s *Server) reconnect(h *t.Host) error {
+	if h.Conn != nil {
+		h.Conn.Close()
+		h.Conn = nil
+	}
+	return s.dial(h)
+}
+
+// dial connects to the host and sends it our identifier for it, so that a restarted host reports results under the right one. Host should be locked.
+func (s *Server) dial(h *t.Host) error {
 	connection, err := net.DialTimeout("tcp", string(h.Endpoint), defaultTimeoutServerToServer)
 	if err != nil {
 		h.Conn = nil
-		h.IsAlive = false
+		h.SetAlive(false)
 		return err
 	}
 
 	//log.Printf("Connected to %v", h)
 
 	h.Conn = rpc.NewClient(connection)
+
+	err = s.setIdentifier(h)
+	if err != nil {
+		h.Conn.Close()
+		h.Conn = nil
+		h.SetAlive(false)
+		return err
+	}
 	return nil
 }
 
@@ -53,6 +79,8 @@ func (s *Server) healthcheck(h *t.Host) (err error) {
 	h.LastHealthCheckAttempt = &tm
 	h.Unlock()
 
+	defer s.metrics.observeHealthcheck(h.Endpoint, tm)
+
 	err = s.connect(h)
 	if err != nil {
 		return err
@@ -66,15 +94,12 @@ func (s *Server) healthcheck(h *t.Host) (err error) {
 	out := wrpc.HealthCheckOutput{}
 	in := wrpc.HealthCheckInput{CommonInput: s.newCommonInput()}
 
-	call := h.Conn.Go(wrpc.BuildMethod("HealthCheck"), in, &out, nil)
-	select {
-	case ret := <-call.Done:
-		err = ret.Error
-	case <-time.After(rpcHealthCheckTimeout):
-		err = errors.New("RPC call timed out")
+	err = s.hello(h)
+	if err == nil {
+		err = callWithTimeout(h.Conn, wrpc.BuildMethod("HealthCheck"), in, &out, rpcHealthCheckTimeout)
 	}
 	if err != nil {
-		h.IsAlive = false
+		h.SetAlive(false)
 
 		// Close HC-failed connection so that we reconnect the next time
 		h.Conn.Close()
@@ -82,24 +107,81 @@ func (s *Server) healthcheck(h *t.Host) (err error) {
 		return err
 	}
 
-	h.IsAlive = out.Ok
+	h.SetAlive(out.Ok)
 	return nil
 }
 
-func (s *Server) setIdentifier(h *t.Host) (err error) {
-	err = s.connect(h)
+// hello exchanges version information with the host and records it in h.Info. Host should be locked.
+func (s *Server) hello(h *t.Host) error {
+	out := wrpc.HelloOutput{}
+	in := wrpc.HelloInput{
+		CommonInput:  s.newCommonInput(),
+		ProtoVersion: wrpc.ProtoVersion,
+		BuildVersion: werify.Version,
+	}
+
+	err := callWithTimeout(h.Conn, wrpc.BuildHelloMethod(), in, &out, rpcHealthCheckTimeout)
+	if e, ok := err.(rpc.ServerError); ok && strings.HasPrefix(string(e), "rpc: can't find service") {
+		// Older werifyd without Hello. Let the health check decide if we can talk to it.
+		if !h.IsAlive {
+			log.Printf("Hello not supported by %v, version unknown: %s", h, err.Error())
+		}
+		h.Info = wrpc.HostInfo{}
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	h.Lock()
-	defer h.Unlock()
+	if h.Info.BuildVersion != out.BuildVersion {
+		log.Printf("%v is running build %s", h, out.BuildVersion)
+	}
 
+	// Recorded even on a mismatch, so that the host list shows what the host is running
+	h.Info = wrpc.HostInfo{
+		ProtoVersion:       out.ProtoVersion,
+		BuildVersion:       out.BuildVersion,
+		CheckTypes:         out.CheckTypes,
+		DisabledCheckTypes: out.DisabledCheckTypes,
+		Capabilities:       out.Capabilities,
+		Hostname:           out.Hostname,
+	}
+
+	if out.ProtoVersion != wrpc.ProtoVersion {
+		return fmt.Errorf("protocol version mismatch: host has %s (build %s), we have %s", out.ProtoVersion, out.BuildVersion, wrpc.ProtoVersion)
+	}
+	return nil
+}
+
+// setIdentifier sends the host its identifier. Host should be locked.
+func (s *Server) setIdentifier(h *t.Host) error {
 	out := wrpc.SetIdentifierOutput{}
 	in := wrpc.SetIdentifierInput{
 		CommonInput: s.newCommonInput(),
 		Identifier:  wrpc.ServerIdentifier(h.Endpoint),
 	}
 
-	return h.Conn.Call(wrpc.BuildMethod("SetIdentifier"), in, &out)
+	return callWithTimeout(h.Conn, wrpc.BuildMethod("SetIdentifier"), in, &out, defaultTimeoutServerToServer)
+}
+
+// callWithTimeout makes an RPC call, giving up after timeout
+func callWithTimeout(conn *rpc.Client, method string, in, out interface{}, timeout time.Duration) error {
+	call := conn.Go(method, in, out, nil)
+	select {
+	case ret := <-call.Done:
+		return ret.Error
+	case <-time.After(timeout):
+		return errors.New("RPC call timed out")
+	}
+}
+
+// isConnectionError returns true if the error is from connecting to a host or from a lost connection (refused, reset, broken pipe), as opposed to an error returned by the host or a timeout
+func isConnectionError(err error) bool {
+	if err == rpc.ErrShutdown || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	if e, ok := err.(*net.OpError); ok && !e.Timeout() {
+		return true
+	}
+	return false
 }
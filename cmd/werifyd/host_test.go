@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+
+	t "github.com/disq/werify/cmd/werifyd/types"
+	wrpc "github.com/disq/werify/rpc"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsConnectionError(tt *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"shutdown", rpc.ErrShutdown, true},
+		{"unexpected eof", io.ErrUnexpectedEOF, true},
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, true},
+		{"reset", &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{"broken pipe", &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}, true},
+		{"dial timeout", &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}, false},
+		{"read timeout", &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, false},
+		{"rpc timeout", errors.New("RPC call timed out"), false},
+		{"server error", rpc.ServerError("env mismatch"), false},
+	}
+
+	for _, tc := range tests {
+		if got := isConnectionError(tc.err); got != tc.want {
+			tt.Errorf("%s: isConnectionError(%v) = %v, want %v", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
+
+// testHost is a werifyd serving RPC on a local address, which can be stopped and started again on the same address
+type testHost struct {
+	addr string
+	s    *Server
+
+	ln    net.Listener
+	conns []net.Conn
+	mu    sync.Mutex
+}
+
+// start runs a fresh werifyd, without an identifier, on h.addr (or a random port the first time)
+func (h *testHost) start(tt *testing.T) {
+	addr := h.addr
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		tt.Fatalf("listen: %s", err.Error())
+	}
+	h.addr = ln.Addr().String()
+	h.ln = ln
+	h.s = &Server{context: context.Background(), numWorkers: 2, metrics: newMetrics()}
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(wrpc.ProtoVersion, h.s); err != nil {
+		tt.Fatalf("register: %s", err.Error())
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			h.mu.Lock()
+			h.conns = append(h.conns, conn)
+			h.mu.Unlock()
+			go srv.ServeConn(conn)
+		}
+	}()
+}
+
+// stop closes the listener and all the connections, as if the process exited
+func (h *testHost) stop() {
+	h.ln.Close()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range h.conns {
+		c.Close()
+	}
+	h.conns = nil
+}
+
+func TestReconnectSetsIdentifier(tt *testing.T) {
+	th := &testHost{}
+	th.start(tt)
+	defer func() { th.stop() }()
+
+	s := &Server{context: context.Background(), metrics: newMetrics()}
+	h := &t.Host{Endpoint: wrpc.Endpoint(th.addr)}
+	want := wrpc.ServerIdentifier(h.Endpoint)
+
+	if err := s.connect(h); err != nil {
+		tt.Fatalf("connect: %s", err.Error())
+	}
+	if th.s.identifier != want {
+		tt.Fatalf("identifier after connect = %q, want %q", th.s.identifier, want)
+	}
+
+	th.stop()
+	th.start(tt)
+
+	h.Lock()
+	defer h.Unlock()
+
+	in := wrpc.HealthCheckInput{CommonInput: s.newCommonInput()}
+	out := wrpc.HealthCheckOutput{}
+
+	// First attempt goes to the old connection
+	err := callWithTimeout(h.Conn, wrpc.BuildMethod("HealthCheck"), in, &out, rpcHealthCheckTimeout)
+	if err == nil || !isConnectionError(err) {
+		tt.Fatalf("call after restart: got %v, want a connection error", err)
+	}
+
+	// Second attempt
+	if err := s.reconnect(h); err != nil {
+		tt.Fatalf("reconnect: %s", err.Error())
+	}
+	if err := callWithTimeout(h.Conn, wrpc.BuildMethod("HealthCheck"), in, &out, rpcHealthCheckTimeout); err != nil {
+		tt.Fatalf("call after reconnect: %s", err.Error())
+	}
+	if th.s.identifier != want {
+		tt.Errorf("identifier after reconnect = %q, want %q", th.s.identifier, want)
+	}
+}
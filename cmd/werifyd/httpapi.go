@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	wrpc "github.com/disq/werify/rpc"
+)
+
+// httpEnvHeader is the HTTP header to pass the env tag in, as an alternative to the EnvTag field in the request body
+const httpEnvHeader = "X-Werify-Env"
+
+// httpAPIPrefix is the path prefix of all HTTP API endpoints
+const httpAPIPrefix = "/v1/"
+
+// httpError is the JSON body returned on errors
+type httpError struct {
+	Error string `json:"error"`
+}
+
+// newHTTPHandler returns the HTTP/JSON API gateway, which calls the same handlers as the RPC server
+//
+// Routes:
+//
+//	POST   /v1/hosts               AddHost
+//	DELETE /v1/hosts               RemoveHost
+//	GET    /v1/hosts               ListHost (?active=false or ?inactive=false to filter)
+//	POST   /v1/hosts/labels        LabelHost
+//	POST   /v1/operations          RunOperation (always forwarded, returns a handle)
+//	GET    /v1/operations/<handle> OperationStatusCheck
+//	POST   /v1/plan                PlanOperation (always forwarded)
+//	POST   /v1/refresh             Refresh
+func (s *Server) newHTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(httpAPIPrefix+"hosts", s.httpHosts)
+	mux.HandleFunc(httpAPIPrefix+"hosts/labels", s.httpLabels)
+	mux.HandleFunc(httpAPIPrefix+"operations", s.httpOperations)
+	mux.HandleFunc(httpAPIPrefix+"operations/", s.httpOperationStatus)
+	mux.HandleFunc(httpAPIPrefix+"plan", s.httpPlan)
+	mux.HandleFunc(httpAPIPrefix+"refresh", s.httpRefresh)
+	return mux
+}
+
+func (s *Server) httpHosts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		in := wrpc.AddHostInput{}
+		if !decodeHTTPInput(w, r, &in, &in.CommonInput) {
+			return
+		}
+		out := wrpc.AddHostOutput{}
+		writeHTTPOutput(w, &out, s.AddHost(in, &out))
+
+	case http.MethodDelete:
+		in := wrpc.RemoveHostInput{}
+		if !decodeHTTPInput(w, r, &in, &in.CommonInput) {
+			return
+		}
+		if e := r.URL.Query().Get("endpoint"); e != "" {
+			in.Endpoint = wrpc.Endpoint(e)
+		}
+		out := wrpc.RemoveHostOutput{}
+		writeHTTPOutput(w, &out, s.RemoveHost(in, &out))
+
+	case http.MethodGet:
+		in := wrpc.ListHostsInput{ListActive: true, ListInactive: true}
+		if !decodeHTTPInput(w, r, nil, &in.CommonInput) {
+			return
+		}
+		q := r.URL.Query()
+		if v, err := strconv.ParseBool(q.Get("active")); err == nil {
+			in.ListActive = v
+		}
+		if v, err := strconv.ParseBool(q.Get("inactive")); err == nil {
+			in.ListInactive = v
+		}
+		out := wrpc.ListHostsOutput{}
+		writeHTTPOutput(w, &out, s.ListHost(in, &out))
+
+	default:
+		writeHTTPError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *Server) httpLabels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeHTTPError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	in := wrpc.LabelHostInput{}
+	if !decodeHTTPInput(w, r, &in, &in.CommonInput) {
+		return
+	}
+	out := wrpc.LabelHostOutput{}
+	writeHTTPOutput(w, &out, s.LabelHost(in, &out))
+}
+
+func (s *Server) httpOperations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeHTTPError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	in := wrpc.OperationInput{}
+	if !decodeHTTPInput(w, r, &in, &in.CommonInput) {
+		return
+	}
+	// Same as werifyctl: the gateway only ever starts async operations
+	in.Forward = true
+
+	out := wrpc.OperationOutput{}
+	writeHTTPOutput(w, &out, s.RunOperation(in, &out))
+}
+
+func (s *Server) httpPlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeHTTPError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	in := wrpc.OperationInput{}
+	if !decodeHTTPInput(w, r, &in, &in.CommonInput) {
+		return
+	}
+	in.Forward = true
+
+	out := wrpc.PlanOutput{}
+	writeHTTPOutput(w, &out, s.PlanOperation(in, &out))
+}
+
+func (s *Server) httpOperationStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeHTTPError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	in := wrpc.OperationStatusCheckInput{
+		Handle: strings.TrimPrefix(r.URL.Path, httpAPIPrefix+"operations/"),
+	}
+	if !decodeHTTPInput(w, r, nil, &in.CommonInput) {
+		return
+	}
+	out := wrpc.OperationStatusCheckOutput{}
+	writeHTTPOutput(w, &out, s.OperationStatusCheck(in, &out))
+}
+
+func (s *Server) httpRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeHTTPError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	in := wrpc.RefreshInput{}
+	if !decodeHTTPInput(w, r, &in, &in.CommonInput) {
+		return
+	}
+	out := wrpc.RefreshOutput{}
+	writeHTTPOutput(w, &out, s.Refresh(in, &out))
+}
+
+// decodeHTTPInput decodes the request body (if any) into input, and sets the env tag from the header if present.
+// If it returns false, an error response is already written.
+func decodeHTTPInput(w http.ResponseWriter, r *http.Request, input interface{}, ci *wrpc.CommonInput) bool {
+	if input != nil && r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(input)
+		if err != nil && err != io.EOF {
+			writeHTTPError(w, http.StatusBadRequest, err)
+			return false
+		}
+	}
+
+	if env := r.Header.Get(httpEnvHeader); env != "" {
+		ci.EnvTag = env
+	}
+	return true
+}
+
+func writeHTTPOutput(w http.ResponseWriter, output interface{}, err error) {
+	if err == errEnvMismatch {
+		writeHTTPError(w, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, output)
+}
+
+func writeHTTPError(w http.ResponseWriter, code int, err error) {
+	writeHTTPJSON(w, code, httpError{Error: err.Error()})
+}
+
+func writeHTTPJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Writing HTTP response: %s", err.Error())
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"log"
 	"math/rand"
 	"net"
+	"net/http"
 	"net/rpc"
+	"net/rpc/jsonrpc"
 	"os"
 	"os/signal"
 	"runtime"
@@ -22,6 +24,11 @@ func main() {
 	env := flag.String("env", werify.DefaultEnv, "Env tag")
 	port := flag.Int("port", werify.DefaultPort, "Listen on port")
 	numWorkers := flag.Int("w", runtime.NumCPU(), "Number of workers per operation")
+	httpPort := flag.Int("http", 0, "Listen on port for the HTTP/JSON API (0 to disable)")
+	jsonrpcPort := flag.Int("jsonrpc", 0, "Listen on port for JSON-RPC connections (0 to disable)")
+	metricsPort := flag.Int("metrics", 0, "Listen on port for Prometheus metrics (0 to disable)")
+	pluginDir := flag.String("plugins", "", "Directory of exec_plugin executables (empty to disable)")
+	allowCommands := flag.Bool("allow-commands", false, "Enable the command check type, running arbitrary commands")
 
 	flag.Parse()
 
@@ -36,22 +43,68 @@ func main() {
 
 	rand.Seed(time.Now().UnixNano())
 
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Printf("Could not get hostname: %s", err.Error())
+	}
+
 	s := &Server{
 		context:          ctx,
 		env:              *env,
+		hostname:         hostname,
 		numWorkers:       *numWorkers,
+		pluginDir:        *pluginDir,
+		allowCommands:    *allowCommands,
 		opBuffer:         make(map[string]wrpc.OperationOutput),
 		forceHealthcheck: make(chan struct{}, 10),
+		metrics:          newMetrics(),
+	}
+
+	if *httpPort > 0 {
+		s.capabilities = append(s.capabilities, "http")
+	}
+	if *jsonrpcPort > 0 {
+		s.capabilities = append(s.capabilities, "jsonrpc")
+	}
+	if *metricsPort > 0 {
+		s.capabilities = append(s.capabilities, "metrics")
 	}
 
-	err := rpc.RegisterName(wrpc.ProtoVersion, s)
+	err = rpc.RegisterName(wrpc.ProtoVersion, s)
 	if err != nil {
 		log.Fatalf("Registering RPC server: %s", err.Error())
 	}
+	err = rpc.RegisterName(wrpc.HelloService, &helloService{s: s})
+	if err != nil {
+		log.Fatalf("Registering RPC hello service: %s", err.Error())
+	}
+
+	listener := listen(ctx, *port, "RPC")
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if *httpPort > 0 {
+		go http.Serve(listen(ctx, *httpPort, "HTTP"), s.newHTTPHandler())
+	}
+
+	if *metricsPort > 0 {
+		mux := http.NewServeMux()
+		mux.HandleFunc(metricsPath, s.serveMetrics)
+		go http.Serve(listen(ctx, *metricsPort, "metrics"), mux)
+	}
+
+	if *jsonrpcPort > 0 {
+		go acceptJSONRPC(listen(ctx, *jsonrpcPort, "JSON-RPC"))
+	}
+
+	go s.healthchecker()
+
+	rpc.Accept(listener)
+}
+
+// listen binds to the given port or dies trying. The listener is closed when ctx is done.
+func listen(ctx context.Context, port int, what string) net.Listener {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
-		log.Fatalf("Could not bind: %s", err.Error())
+		log.Fatalf("Could not bind %s: %s", what, err.Error())
 	}
 
 	go func() {
@@ -59,7 +112,17 @@ func main() {
 		listener.Close()
 	}()
 
-	go s.healthchecker()
+	return listener
+}
 
-	rpc.Accept(listener)
+// acceptJSONRPC is rpc.Accept with the JSON-RPC codec, serving the same registered services
+func acceptJSONRPC(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Printf("JSON-RPC accept: %s", err.Error())
+			return
+		}
+		go jsonrpc.ServeConn(conn)
+	}
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/disq/werify/cmd/werifyd/pool"
+	wrpc "github.com/disq/werify/rpc"
+)
+
+// metricsPath is the HTTP path the metrics are served on
+const metricsPath = "/metrics"
+
+// metrics collects the stats which aren't readily available from the Server struct
+type metrics struct {
+	mu sync.Mutex
+
+	// rpc* are per rpc method
+	rpcCalls    map[string]uint64
+	rpcErrors   map[string]uint64
+	rpcDuration map[string]float64
+
+	// healthcheckLatency is the duration of the last health check, per host
+	healthcheckLatency map[wrpc.Endpoint]float64
+
+	// checkResults is the results of the latest ended operation
+	checkResults map[wrpc.ServerIdentifier]map[string]wrpc.OperationResult
+
+	operationsInFlight int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		rpcCalls:           make(map[string]uint64),
+		rpcErrors:          make(map[string]uint64),
+		rpcDuration:        make(map[string]float64),
+		healthcheckLatency: make(map[wrpc.Endpoint]float64),
+	}
+}
+
+// observeRPC records an rpc call. Meant to be deferred, hence the error pointer.
+func (m *metrics) observeRPC(method string, started time.Time, err *error) {
+	d := time.Since(started).Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rpcCalls[method]++
+	m.rpcDuration[method] += d
+	if *err != nil {
+		m.rpcErrors[method]++
+	}
+}
+
+func (m *metrics) observeHealthcheck(e wrpc.Endpoint, started time.Time) {
+	d := time.Since(started).Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.healthcheckLatency[e] = d
+}
+
+func (m *metrics) forgetHost(e wrpc.Endpoint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.healthcheckLatency, e)
+}
+
+func (m *metrics) setCheckResults(res map[wrpc.ServerIdentifier]map[string]wrpc.OperationResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkResults = res
+}
+
+func (m *metrics) operationStarted() {
+	atomic.AddInt64(&m.operationsInFlight, 1)
+}
+
+func (m *metrics) operationEnded() {
+	atomic.AddInt64(&m.operationsInFlight, -1)
+}
+
+// serveMetrics is the HTTP handler for the metrics endpoint, in Prometheus text exposition format
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	s.hostMu.RLock()
+	alive := make(map[string]float64, len(s.hosts))
+	for _, h := range s.hosts {
+		// Don't wait for the lock, which is held during operations
+		alive[string(h.Endpoint)] = boolToFloat(h.Alive())
+	}
+	s.hostMu.RUnlock()
+	writeMetric(w, "werify_host_alive", "gauge", "Whether the host passed its last health check", "endpoint", alive)
+
+	s.opMu.RLock()
+	opBufferSize := float64(len(s.opBuffer))
+	s.opMu.RUnlock()
+	writeMetric(w, "werify_opbuffer_size", "gauge", "Number of operations in the operation buffer", "", map[string]float64{"": opBufferSize})
+
+	running, busy := pool.Stats()
+	writeMetric(w, "werify_pool_workers", "gauge", "Number of running worker pool workers", "", map[string]float64{"": float64(running)})
+	writeMetric(w, "werify_pool_workers_busy", "gauge", "Number of worker pool workers processing data", "", map[string]float64{"": float64(busy)})
+
+	m := s.metrics
+	writeMetric(w, "werify_operations_in_flight", "gauge", "Number of forwarded operations still running", "", map[string]float64{"": float64(atomic.LoadInt64(&m.operationsInFlight))})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	latency := make(map[string]float64, len(m.healthcheckLatency))
+	for e, v := range m.healthcheckLatency {
+		latency[string(e)] = v
+	}
+	writeMetric(w, "werify_healthcheck_latency_seconds", "gauge", "Duration of the last health check", "endpoint", latency)
+
+	calls := make(map[string]float64, len(m.rpcCalls))
+	errs := make(map[string]float64, len(m.rpcCalls))
+	for k, v := range m.rpcCalls {
+		calls[k] = float64(v)
+		errs[k] = float64(m.rpcErrors[k])
+	}
+	writeMetric(w, "werify_rpc_calls_total", "counter", "Number of handled RPC calls", "method", calls)
+	writeMetric(w, "werify_rpc_errors_total", "counter", "Number of handled RPC calls which returned an error", "method", errs)
+
+	fmt.Fprintf(w, "# HELP werify_rpc_duration_seconds Duration of handled RPC calls\n# TYPE werify_rpc_duration_seconds summary\n")
+	for _, k := range sortedKeys(m.rpcDuration) {
+		fmt.Fprintf(w, "werify_rpc_duration_seconds_sum{method=\"%s\"} %g\n", escapeLabel(k), m.rpcDuration[k])
+		fmt.Fprintf(w, "werify_rpc_duration_seconds_count{method=\"%s\"} %d\n", escapeLabel(k), m.rpcCalls[k])
+	}
+
+	fmt.Fprintf(w, "# HELP werify_check_success Whether the check succeeded in the latest ended operation\n# TYPE werify_check_success gauge\n")
+	ids := make([]string, 0, len(m.checkResults))
+	for id := range m.checkResults {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		res := m.checkResults[wrpc.ServerIdentifier(id)]
+		names := make([]string, 0, len(res))
+		for name := range res {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "werify_check_success{host=\"%s\",check=\"%s\"} %g\n", escapeLabel(id), escapeLabel(name), boolToFloat(res[name].Success))
+		}
+	}
+}
+
+// writeMetric writes a metric with a single label (or none, if label is empty) in the text exposition format
+func writeMetric(w io.Writer, name, typ, help, label string, values map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	for _, k := range sortedKeys(values) {
+		if label == "" {
+			fmt.Fprintf(w, "%s %g\n", name, values[k])
+		} else {
+			fmt.Fprintf(w, "%s{%s=\"%s\"} %g\n", name, label, escapeLabel(k), values[k])
+		}
+	}
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -1,23 +1,41 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/disq/werify/cmd/werifyd/checkers"
 	"github.com/disq/werify/cmd/werifyd/pool"
 	t "github.com/disq/werify/cmd/werifyd/types"
 	wrpc "github.com/disq/werify/rpc"
 )
 
+// rpcOperationTimeout is the minimum time a host is given to run all the ops in a forwarded operation
 const rpcOperationTimeout = 30 * time.Second
 
+// defaultOperationTimeout is the timeout of a single check if the Operation doesn't set one
+const defaultOperationTimeout = 25 * time.Second
+
+// defaultRetryInterval is the duration between check attempts if the Operation doesn't set one
+const defaultRetryInterval = time.Second
+
+// rpcOperationRetries is the number of times a failed RunOperation call to a host is retried, on a new connection
+const rpcOperationRetries = 2
+
+// rpcRetryInterval is the duration between RunOperation call attempts
+const rpcRetryInterval = time.Second
+
+// rpcTimeoutMargin is added to the longest operation timeout to get the call timeout, and to the call timeout to get how long we wait for the reply
+const rpcTimeoutMargin = 5 * time.Second
+
 // OperationStatusCheck is the rpc handler to check the status of an ongoing or ended operation
 func (s *Server) OperationStatusCheck(input wrpc.OperationStatusCheckInput, output *wrpc.OperationStatusCheckOutput) error {
-	return s.rpcMiddleware(&input.CommonInput, func() error {
+	return s.rpcMiddleware("OperationStatusCheck", &input.CommonInput, func() error {
 		o := s.getOpBuffer(input.Handle)
 		if o == nil {
 			return errors.New("Invalid handle")
@@ -26,6 +44,8 @@ func (s *Server) OperationStatusCheck(input wrpc.OperationStatusCheckInput, outp
 		output.Results = o.Results
 		output.StartedAt = o.StartedAt
 		output.EndedAt = o.EndedAt
+		output.Aborted = o.Aborted
+		output.AbortReason = o.AbortReason
 		return nil
 	})
 }
@@ -36,12 +56,17 @@ func (s *Server) generateHandle() string {
 	return fmt.Sprintf("%s%d", randStringBytes(3), val)
 }
 
+// setOpBuffer stores a copy of the operation output, so that the caller can keep updating it
 func (s *Server) setOpBuffer(handle string, o *wrpc.OperationOutput) {
+	c := *o
+	c.Results = copyResults(o.Results)
+
 	s.opMu.Lock()
 	defer s.opMu.Unlock()
-	s.opBuffer[handle] = *o
+	s.opBuffer[handle] = c
 }
 
+// getOpBuffer returns a copy of the operation output, which is safe to use while the operation is running
 func (s *Server) getOpBuffer(handle string) *wrpc.OperationOutput {
 	s.opMu.RLock()
 	defer s.opMu.RUnlock()
@@ -50,14 +75,35 @@ func (s *Server) getOpBuffer(handle string) *wrpc.OperationOutput {
 	if !ok {
 		return nil
 	}
+	o.Results = copyResults(o.Results)
 	return &o
 }
 
+// copyResults returns a deep copy of the results map
+func copyResults(res map[wrpc.ServerIdentifier]map[string]wrpc.OperationResult) map[wrpc.ServerIdentifier]map[string]wrpc.OperationResult {
+	if res == nil {
+		return nil
+	}
+	ret := make(map[wrpc.ServerIdentifier]map[string]wrpc.OperationResult, len(res))
+	for id, r := range res {
+		c := make(map[string]wrpc.OperationResult, len(r))
+		for k, v := range r {
+			c[k] = v
+		}
+		ret[id] = c
+	}
+	return ret
+}
+
 // RunOperation is the rpc handler to run a host check operation
 func (s *Server) RunOperation(input wrpc.OperationInput, output *wrpc.OperationOutput) error {
-	return s.rpcMiddleware(&input.CommonInput, func() error {
+	return s.rpcMiddleware("RunOperation", &input.CommonInput, func() error {
 
 		if input.Forward {
+			if err := input.Strategy.Validate(); err != nil {
+				return err
+			}
+
 			// Forward checks to alive hosts in a worker pool and reap results
 			// But first generate a Handle and return it
 			handle := s.generateHandle()
@@ -67,36 +113,69 @@ func (s *Server) RunOperation(input wrpc.OperationInput, output *wrpc.OperationO
 			return nil
 		}
 
+		callTimeout, err := input.GetTimeout()
+		if err != nil {
+			return err
+		}
+
+		// Checks still running or waiting for a worker when the call times out are reported as such, with the results of the rest
+		ctx := s.context
+		if callTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, callTimeout)
+			defer cancel()
+		}
+
 		output.StartedAt = time.Now()
 		output.Results = make(map[wrpc.ServerIdentifier]map[string]wrpc.OperationResult)
 
+		// Checks which fail to render are reported without running them
+		res := map[string]wrpc.OperationResult{}
+		if !input.Rendered {
+			input, res = renderOps(input, s.localTemplateData(input.Vars))
+		}
+
 		ch := make(chan t.PoolData)
-		p := pool.NewPool(s.context, ch)
+		p := pool.NewPool(ctx, ch)
 
 		// Really run the checks, return results
 
 		var mu sync.Mutex
-		res := map[string]wrpc.OperationResult{}
 
 		p.Start(s.numWorkers, func(pd t.PoolData) {
-			r := s.operationRunner(pd.GetOperation())
+			r := s.operationRunner(ctx, pd.GetOperation())
 			mu.Lock()
 			defer mu.Unlock()
 			res[pd.GetName()] = *r
 		})
 
+	feed:
 		for opId, op := range input.Ops {
 			// Run each Operation in a worker concurrently
 			o := t.WorkerOperation{
 				Name:      opId,
 				Operation: op,
 			}
-			ch <- &o
+			select {
+			case ch <- &o:
+			case <-ctx.Done():
+				break feed
+			}
 		}
 
 		close(ch)
 		p.Wait()
 
+		for opId, op := range input.Ops {
+			if _, ok := res[opId]; !ok {
+				res[opId] = wrpc.OperationResult{
+					Err:         "Not run, operation deadline reached",
+					Status:      wrpc.StatusUnknown,
+					Description: op.Description,
+				}
+			}
+		}
+
 		output.Results[s.identifier] = res
 		tm := time.Now()
 		output.EndedAt = &tm
@@ -109,10 +188,15 @@ func (s *Server) runAsyncOperation(handle string, input wrpc.OperationInput) {
 	// TODO further distribute work among peers using Forward=true calls?
 	input.Forward = false
 
-	ch := make(chan t.PoolData)
-	p := pool.NewPool(s.context, ch)
+	s.metrics.operationStarted()
+	defer s.metrics.operationEnded()
 
 	rpcCmd := wrpc.BuildMethod(wrpc.RunOperationRpcCommand)
+
+	// Hosts return what they have at the call timeout, so we get the finished results even if some checks take too long
+	callTimeout := operationCallTimeout(input.Ops)
+	input.Timeout = callTimeout.String()
+	rpcTimeout := callTimeout + rpcTimeoutMargin
 	var mu sync.Mutex
 
 	output := wrpc.OperationOutput{
@@ -121,7 +205,10 @@ func (s *Server) runAsyncOperation(handle string, input wrpc.OperationInput) {
 	}
 	s.setOpBuffer(handle, &output)
 
-	p.Start(s.numWorkers, func(pd t.PoolData) {
+	// ran and failed are the number of hosts the operation ran on, and the failed ones among them (see hasCriticalResult)
+	var ran, failed int
+
+	runHost := func(pd t.PoolData) {
 		h := pd.GetHost()
 
 		h.Lock()
@@ -131,82 +218,461 @@ func (s *Server) runAsyncOperation(handle string, input wrpc.OperationInput) {
 			return
 		}
 
+		// Don't send the checks the host can't run, report them as errors instead
+		hostInput, skipped := splitSupportedOps(h, input)
+		if len(skipped) > 0 {
+			log.Printf("Skipping %d unsupported checks on %v (build %s)", len(skipped), h, h.Info.BuildVersion)
+		}
+
+		hostInput, renderFailed := renderOps(hostInput, s.hostTemplateData(h, input.Vars))
+		for k, v := range renderFailed {
+			skipped[k] = v
+		}
+
 		out := wrpc.OperationOutput{}
 
 		var err error
 
-		call := h.Conn.Go(rpcCmd, input, &out, nil)
-		select {
-		case ret := <-call.Done:
-			err = ret.Error
-		case <-time.After(rpcOperationTimeout):
-			err = errors.New("RPC call timed out")
+		if len(hostInput.Ops) > 0 {
+			for attempt := 0; ; attempt++ {
+				if attempt > 0 {
+					err = s.reconnect(h)
+				}
+				if err == nil {
+					err = callWithTimeout(h.Conn, rpcCmd, hostInput, &out, rpcTimeout)
+				}
+				// Only retry connection failures. A host which timed out might still be running the checks, and checks can have side effects.
+				if err == nil || attempt == rpcOperationRetries || !isConnectionError(err) {
+					break
+				}
+
+				log.Printf("RunOperation failed on %v, retrying: %s", h, err.Error())
+				time.Sleep(rpcRetryInterval)
+				out = wrpc.OperationOutput{}
+			}
 		}
 
 		mu.Lock()
 		defer mu.Unlock()
+
+		// We won't know the identifier of the server if the call fails, so make one from the Endpoint (it should match, else we wouldn't have added this Host to our list)
+		hostId := wrpc.ServerIdentifier(h.Endpoint)
+
+		ran++
+		hostFailed := false
+		defer func() {
+			if hostFailed {
+				failed++
+			}
+		}()
+
 		if err != nil {
 			// A failed RPC call is a failed RPC call for all the commands.
-			// We won't know the identifier of the server, so make one from the Endpoint (it should match, else we wouldn't have added this Host to our list)
-			id := wrpc.ServerIdentifier(h.Endpoint)
-			output.Results[id] = make(map[string]wrpc.OperationResult)
-			for k := range input.Ops {
-				s := output.Results[id][k]
+			output.Results[hostId] = skipped
+			for k := range hostInput.Ops {
+				s := output.Results[hostId][k]
 				s.Err = err.Error()
-				output.Results[id][k] = s
+				s.Status = wrpc.StatusUnknown
+				output.Results[hostId][k] = s
 			}
+			setDescriptions(output.Results[hostId], input.Ops)
+			// We don't know how the checks went, so count it as failed to stop a rollout taking hosts down
+			hostFailed = true
 			s.setOpBuffer(handle, &output)
 			return
 		}
 
+		if len(out.Results) == 0 && len(skipped) > 0 {
+			out.Results = map[wrpc.ServerIdentifier]map[string]wrpc.OperationResult{hostId: {}}
+		}
 		for id, r := range out.Results {
+			for k, v := range skipped {
+				r[k] = v
+			}
+			setDescriptions(r, input.Ops)
 			output.Results[id] = r
+
+			hostFailed = hostFailed || hasCriticalResult(r)
 		}
 		s.setOpBuffer(handle, &output)
 		return
-	})
+	}
+
+	hosts := s.aliveHosts()
+	pause, _ := input.Strategy.GetPause() // Validated in RunOperation
+	maxFailure := input.Strategy.MaxFailurePercent
+
+	for i, n := range input.Strategy.BatchSizes(len(hosts)) {
+		if i > 0 && pause > 0 {
+			select {
+			case <-time.After(pause):
+			case <-s.context.Done():
+			}
+		}
+		if s.context.Err() != nil {
+			abortOperation(&output, input.Ops, hosts, "Shutting down")
+			break
+		}
+
+		batch := hosts[:n]
+		hosts = hosts[n:]
+
+		ch := make(chan t.PoolData)
+		p := pool.NewPool(s.context, ch)
+		p.Start(s.numWorkers, runHost)
+
+		for _, h := range batch {
+			// Run each RPC call for each Host in a worker concurrently
+			ch <- h
+		}
+
+		close(ch)
+		p.Wait()
+
+		if len(hosts) > 0 {
+			if reason, exceeded := failureRateExceeded(failed, ran, maxFailure); exceeded {
+				abortOperation(&output, input.Ops, hosts, reason)
+				break
+			}
+		}
+	}
+
+	tm := time.Now()
+	output.EndedAt = &tm
+	s.setOpBuffer(handle, &output)
+	s.metrics.setCheckResults(output.Results)
+}
 
+// hasCriticalResult returns true if any of the results of a host is CRITICAL. Warnings, and errors from checks the host can't run, don't make a host failed for the rollout.
+func hasCriticalResult(results map[string]wrpc.OperationResult) bool {
+	for _, r := range results {
+		if r.Status == wrpc.StatusCritical {
+			return true
+		}
+	}
+	return false
+}
+
+// failureRateExceeded returns the reason to abort the operation if the percentage of failed hosts exceeds maxFailure. A nil maxFailure has no limit.
+func failureRateExceeded(failed, ran int, maxFailure *float64) (string, bool) {
+	if maxFailure == nil || ran == 0 {
+		return "", false
+	}
+	rate := float64(failed) * 100 / float64(ran)
+	if rate <= *maxFailure {
+		return "", false
+	}
+	return fmt.Sprintf("Failure rate %.1f%% (%d of %d hosts) exceeds %g%%", rate, failed, ran, *maxFailure), true
+}
+
+// aliveHosts returns the hosts which passed their last health check
+func (s *Server) aliveHosts() []*t.Host {
 	s.hostMu.RLock()
 	defer s.hostMu.RUnlock()
 
+	var ret []*t.Host
 	for _, h := range s.hosts {
-		// Run each RPC call for each Host in a worker concurrently
-		ch <- h
+		h.Lock()
+		if h.IsAlive {
+			ret = append(ret, h)
+		}
+		h.Unlock()
+	}
+	return ret
+}
+
+// abortOperation marks the operation as aborted, with error results for the ops on the hosts it didn't run on
+func abortOperation(output *wrpc.OperationOutput, ops map[string]wrpc.Operation, notRun []*t.Host, reason string) {
+	log.Printf("Aborting operation, skipping %d hosts: %s", len(notRun), reason)
+
+	output.Aborted = true
+	output.AbortReason = reason
+	for _, h := range notRun {
+		res := make(map[string]wrpc.OperationResult, len(ops))
+		for k, op := range ops {
+			res[k] = wrpc.OperationResult{
+				Err:         "Not run, operation aborted",
+				Status:      wrpc.StatusUnknown,
+				Description: op.Description,
+			}
+		}
+		output.Results[wrpc.ServerIdentifier(h.Endpoint)] = res
 	}
+}
 
-	close(ch)
-	p.Wait()
+// operationCallTimeout returns how long a host has to run ops: rpcOperationTimeout, or longer if any of the ops can take longer (with retries)
+func operationCallTimeout(ops map[string]wrpc.Operation) time.Duration {
+	ret := rpcOperationTimeout
+	for _, op := range ops {
+		// Invalid values are reported by the host
+		timeout, err := op.GetTimeout(defaultOperationTimeout)
+		if err != nil {
+			continue
+		}
+		interval, err := op.GetRetryInterval(defaultRetryInterval)
+		if err != nil || op.Retries < 0 {
+			continue
+		}
 
-	tm := time.Now()
-	output.EndedAt = &tm
-	s.setOpBuffer(handle, &output)
+		d := time.Duration(op.Retries+1)*timeout + time.Duration(op.Retries)*interval + rpcTimeoutMargin
+		if d > ret {
+			ret = d
+		}
+	}
+	return ret
 }
 
-// operationRunner runs the Operation (checks) and returns the result
-func (s *Server) operationRunner(op *wrpc.Operation) *wrpc.OperationResult {
-	res := &wrpc.OperationResult{}
+// PlanOperation is the rpc handler to report which operations would run on which hosts, without running them
+func (s *Server) PlanOperation(input wrpc.OperationInput, output *wrpc.PlanOutput) error {
+	return s.rpcMiddleware("PlanOperation", &input.CommonInput, func() error {
+		output.Hosts = make(map[wrpc.ServerIdentifier]map[string]wrpc.PlannedOperation)
 
-	var ok bool
-	var err error
+		if !input.Forward {
+			self := &t.Host{
+				IsAlive: true,
+				Info:    wrpc.HostInfo{CheckTypes: s.supportedCheckTypes(), DisabledCheckTypes: s.disabledCheckTypes()},
+			}
+			output.Hosts[s.identifier] = planHostOps(self, input.Ops, s.localTemplateData(input.Vars))
+			return nil
+		}
+
+		s.hostMu.RLock()
+		defer s.hostMu.RUnlock()
+
+		for _, h := range s.hosts {
+			h.Lock()
+			output.Hosts[wrpc.ServerIdentifier(h.Endpoint)] = planHostOps(h, input.Ops, s.hostTemplateData(h, input.Vars))
+			h.Unlock()
+		}
+		return nil
+	})
+}
+
+// planHostOps reports if each of the ops would run on the host. Host should be locked.
+func planHostOps(h *t.Host, ops map[string]wrpc.Operation, data map[string]interface{}) map[string]wrpc.PlannedOperation {
+	ret := make(map[string]wrpc.PlannedOperation, len(ops))
+
+	for name, op := range ops {
+		var p wrpc.PlannedOperation
+
+		op, renderErr := op.Render(data)
+		if renderErr == nil {
+			op, renderErr = op.Normalize()
+		}
+
+		var validErr error
+		if renderErr == nil {
+			validErr = validateOperation(op)
+		}
+
+		switch {
+		case !h.IsAlive:
+			p.Reason = "Host is not alive"
+		case renderErr != nil:
+			p.Reason = renderErr.Error()
+		case validErr != nil:
+			p.Reason = validErr.Error()
+		case !h.SupportsCheckType(op.OpType):
+			p.Reason = unsupportedReason(h, op.OpType)
+		default:
+			p.Run = true
+			if h.Info.CheckTypes == nil {
+				p.Reason = "Host version unknown, operation type support not verified"
+			}
+		}
+		ret[name] = p
+	}
+	return ret
+}
+
+// validateOperation returns the schema errors of the operation as one error. Only the check types we know about are validated, the host might be running a newer version.
+func validateOperation(op wrpc.Operation) error {
+	if _, ok := wrpc.CheckSchemas[op.OpType]; !ok {
+		return nil
+	}
+
+	var errs []string
+	for _, e := range op.Validate() {
+		errs = append(errs, e.Error())
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New("Invalid operation: " + strings.Join(errs, "; "))
+}
+
+// setDescriptions copies the descriptions of the ops to the results which don't have them: results which we made up, or results from hosts which don't support descriptions
+func setDescriptions(res map[string]wrpc.OperationResult, ops map[string]wrpc.Operation) {
+	for k, r := range res {
+		if r.Description == "" && ops[k].Description != "" {
+			r.Description = ops[k].Description
+			res[k] = r
+		}
+	}
+}
 
-	switch op.OpType {
-	case "file_exists":
-		ok, err = checkers.DoesFileExist(string(op.PathArg))
+// renderOps returns a copy of input with the templates in Ops rendered using data and the Ops normalized, and error results for the Ops which failed
+func renderOps(input wrpc.OperationInput, data map[string]interface{}) (wrpc.OperationInput, map[string]wrpc.OperationResult) {
+	ops := make(map[string]wrpc.Operation, len(input.Ops))
+	failed := make(map[string]wrpc.OperationResult)
 
-	case "file_contains":
-		ok, err = checkers.DoesFileHasWord(string(op.PathArg), string(op.CheckArg))
+	for name, op := range input.Ops {
+		r, err := op.Render(data)
+		if err == nil {
+			r, err = r.Normalize()
+		}
+		if err != nil {
+			failed[name] = wrpc.OperationResult{
+				Err:    err.Error(),
+				Status: wrpc.StatusUnknown,
+			}
+			continue
+		}
+		ops[name] = r
+	}
+
+	input.Ops = ops
+	input.Rendered = true
+	return input, failed
+}
+
+// hostTemplateData returns the data to render templates for the host with. Host should be locked.
+func (s *Server) hostTemplateData(h *t.Host, vars map[string]interface{}) map[string]interface{} {
+	return wrpc.NewTemplateData(h.Endpoint, h.Labels, h.Info.Hostname, s.env, vars)
+}
+
+// localTemplateData returns the data to render templates for operations run directly on this host with
+func (s *Server) localTemplateData(vars map[string]interface{}) map[string]interface{} {
+	return wrpc.NewTemplateData(wrpc.Endpoint(s.identifier), nil, s.hostname, s.env, vars)
+}
+
+// splitSupportedOps returns a copy of input with only the Ops the host supports, and error results for the rest
+func splitSupportedOps(h *t.Host, input wrpc.OperationInput) (wrpc.OperationInput, map[string]wrpc.OperationResult) {
+	ops := make(map[string]wrpc.Operation, len(input.Ops))
+	unsupported := make(map[string]wrpc.OperationResult)
+
+	for name, op := range input.Ops {
+		if h.SupportsCheckType(op.OpType) {
+			ops[name] = op
+			continue
+		}
+		unsupported[name] = wrpc.OperationResult{
+			Err:    unsupportedReason(h, op.OpType),
+			Status: wrpc.StatusUnknown,
+		}
+	}
+
+	input.Ops = ops
+	return input, unsupported
+}
+
+// unsupportedReason returns why the host can't run the check type, which it doesn't support
+func unsupportedReason(h *t.Host, opType wrpc.OperationType) string {
+	if h.IsCheckTypeDisabled(opType) {
+		return disabledCheckTypeError(opType).Error()
+	}
+	return fmt.Sprintf("Unsupported operation type on host: %s", opType)
+}
 
-	case "process_running":
-		ok, err = checkers.IsProcessRunning(string(op.CheckArg), string(op.PathArg))
+// operationRunner runs the Operation (checks) and returns the result. Checks which don't succeed are re-run up to op.Retries times.
+func (s *Server) operationRunner(ctx context.Context, op *wrpc.Operation) *wrpc.OperationResult {
+	var ct checkType
+	var timeout, interval time.Duration
+	var err error
 
-	default:
+	if op.Severity != "" && op.Severity != wrpc.StatusWarning && op.Severity != wrpc.StatusCritical {
+		err = fmt.Errorf("Invalid severity: %s", op.Severity)
+	} else if op.Retries < 0 {
+		err = fmt.Errorf("Invalid retries: %d", op.Retries)
+	} else if timeout, err = op.GetTimeout(defaultOperationTimeout); err != nil {
+		err = fmt.Errorf("Invalid timeout: %s", op.Timeout)
+	} else if interval, err = op.GetRetryInterval(defaultRetryInterval); err != nil {
+		err = fmt.Errorf("Invalid retry interval: %s", op.RetryInterval)
+	} else if c, found := checkTypes[op.OpType]; !found {
 		err = fmt.Errorf("Unhandled operation type: %s", op.OpType)
+	} else if !c.isEnabled(s) {
+		err = disabledCheckTypeError(op.OpType)
+	} else if verr := validateOperation(*op); verr != nil {
+		err = verr
+	} else {
+		ct = c
 	}
 
-	res.Success = ok
+	if err != nil {
+		res := &wrpc.OperationResult{Description: op.Description}
+		setResultStatus(op, res, false, err)
+		return res
+	}
+
+	for attempt := 1; ; attempt++ {
+		res := &wrpc.OperationResult{}
+		ok, err := s.runCheck(ctx, ct, op, res, timeout)
+		setResultStatus(op, res, ok, err)
+		res.Description = op.Description
+
+		if op.Retries > 0 {
+			res.Attempts = attempt
+		}
+		if res.Success || attempt > op.Retries {
+			return res
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return res
+		}
+	}
+}
+
+// setResultStatus sets the Status and Success fields of res from the return values of the check
+func setResultStatus(op *wrpc.Operation, res *wrpc.OperationResult, ok bool, err error) {
 	if err != nil {
 		res.Err = err.Error()
+		res.Status = wrpc.StatusUnknown
+	} else if res.Status == "" {
+		// Pass/fail check
+		res.Status = wrpc.StatusOK
+		if !ok {
+			res.Status = wrpc.StatusCritical
+			if op.Severity != "" {
+				res.Status = op.Severity
+			}
+		}
+	}
+	res.Success = res.Status == wrpc.StatusOK
+}
+
+// runCheck runs the check with a timeout. If the check doesn't return in time (or parent is done), it's abandoned and an error is returned.
+func (s *Server) runCheck(parent context.Context, ct checkType, op *wrpc.Operation, res *wrpc.OperationResult, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	type checkReturn struct {
+		ok  bool
+		err error
+		res wrpc.OperationResult
 	}
 
-	return res
+	// The check fills in its own result, so that an abandoned check can't modify res
+	done := make(chan checkReturn, 1)
+	go func() {
+		var r checkReturn
+		r.ok, r.err = ct.run(ctx, s, op, &r.res)
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		*res = r.res
+		return r.ok, r.err
+	case <-ctx.Done():
+		switch {
+		case parent.Err() == context.DeadlineExceeded:
+			return false, errors.New("Timed out at the operation deadline")
+		case ctx.Err() == context.DeadlineExceeded:
+			return false, fmt.Errorf("Timed out after %s", timeout)
+		}
+		return false, ctx.Err()
+	}
 }
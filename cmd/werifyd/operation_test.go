@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	t "github.com/disq/werify/cmd/werifyd/types"
+	wrpc "github.com/disq/werify/rpc"
+)
+
+func TestHasCriticalResult(tt *testing.T) {
+	tests := []struct {
+		name    string
+		results map[string]wrpc.OperationResult
+		want    bool
+	}{
+		{"none", map[string]wrpc.OperationResult{}, false},
+		{"ok", map[string]wrpc.OperationResult{"a": {Status: wrpc.StatusOK}}, false},
+		{"warning", map[string]wrpc.OperationResult{"a": {Status: wrpc.StatusOK}, "b": {Status: wrpc.StatusWarning}}, false},
+		{"unsupported", map[string]wrpc.OperationResult{"a": {Status: wrpc.StatusUnknown, Err: "Unsupported"}}, false},
+		{"critical", map[string]wrpc.OperationResult{"a": {Status: wrpc.StatusWarning}, "b": {Status: wrpc.StatusCritical}}, true},
+	}
+
+	for _, tc := range tests {
+		if got := hasCriticalResult(tc.results); got != tc.want {
+			tt.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFailureRateExceeded(tt *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name        string
+		failed, ran int
+		max         *float64
+		want        bool
+	}{
+		{"no limit", 3, 3, nil, false},
+		{"nothing ran", 0, 0, f(0), false},
+		{"0% without failures", 0, 5, f(0), false},
+		{"0% with a failure", 1, 5, f(0), true},
+		{"100% all failed", 5, 5, f(100), false},
+		{"at limit", 1, 10, f(10), false},
+		{"over limit", 2, 10, f(10), true},
+		{"fraction", 1, 3, f(33.3), true},
+	}
+
+	for _, tc := range tests {
+		reason, got := failureRateExceeded(tc.failed, tc.ran, tc.max)
+		if got != tc.want {
+			tt.Errorf("%s: got %v (%s), want %v", tc.name, got, reason, tc.want)
+		}
+		if got && reason == "" {
+			tt.Errorf("%s: no reason", tc.name)
+		}
+	}
+}
+
+// TestRunAsyncOperationStrategy runs an operation on three hosts, the first of which is the canary.
+// The check on a host fails if its "path" label is a file which doesn't exist.
+func TestRunAsyncOperationStrategy(tt *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	const missing = "/nonexistent/werify"
+
+	tests := []struct {
+		name        string
+		strategy    wrpc.Strategy
+		severity    wrpc.Status
+		paths       []string
+		unsupported bool // canary host doesn't support the check
+		wantAborted bool
+		wantStatus  []wrpc.Status
+	}{
+		{
+			name:        "abort after canary",
+			strategy:    wrpc.Strategy{Canary: 1, MaxFailurePercent: f(0)},
+			paths:       []string{missing, "/", "/"},
+			wantAborted: true,
+			wantStatus:  []wrpc.Status{wrpc.StatusCritical, wrpc.StatusUnknown, wrpc.StatusUnknown},
+		},
+		{
+			name:       "canary ok",
+			strategy:   wrpc.Strategy{Canary: 1, MaxFailurePercent: f(0)},
+			paths:      []string{"/", missing, "/"},
+			wantStatus: []wrpc.Status{wrpc.StatusOK, wrpc.StatusCritical, wrpc.StatusOK},
+		},
+		{
+			name:       "warnings don't abort",
+			strategy:   wrpc.Strategy{Canary: 1, MaxFailurePercent: f(0)},
+			severity:   wrpc.StatusWarning,
+			paths:      []string{missing, missing, "/"},
+			wantStatus: []wrpc.Status{wrpc.StatusWarning, wrpc.StatusWarning, wrpc.StatusOK},
+		},
+		{
+			name:        "unsupported checks don't abort",
+			strategy:    wrpc.Strategy{Canary: 1, MaxFailurePercent: f(0)},
+			paths:       []string{"/", "/", "/"},
+			unsupported: true,
+			wantStatus:  []wrpc.Status{wrpc.StatusUnknown, wrpc.StatusOK, wrpc.StatusOK},
+		},
+		{
+			name:       "100% doesn't abort",
+			strategy:   wrpc.Strategy{BatchSize: 1, MaxFailurePercent: f(100)},
+			paths:      []string{missing, missing, missing},
+			wantStatus: []wrpc.Status{wrpc.StatusCritical, wrpc.StatusCritical, wrpc.StatusCritical},
+		},
+		{
+			name:        "abort after a batch",
+			strategy:    wrpc.Strategy{BatchSize: 2, MaxFailurePercent: f(40)},
+			paths:       []string{"/", missing, "/"},
+			wantAborted: true,
+			wantStatus:  []wrpc.Status{wrpc.StatusOK, wrpc.StatusCritical, wrpc.StatusUnknown},
+		},
+	}
+
+	for _, tc := range tests {
+		tt.Run(tc.name, func(tt *testing.T) {
+			s := &Server{
+				context:    context.Background(),
+				numWorkers: 2,
+				opBuffer:   make(map[string]wrpc.OperationOutput),
+				metrics:    newMetrics(),
+			}
+
+			for i, path := range tc.paths {
+				th := &testHost{}
+				th.start(tt)
+				defer th.stop()
+
+				h := &t.Host{Endpoint: wrpc.Endpoint(th.addr), Labels: map[string]string{"path": path}}
+				if i == 0 && tc.unsupported {
+					h.Info.CheckTypes = []wrpc.OperationType{"memory"}
+				}
+				if err := s.connect(h); err != nil {
+					tt.Fatalf("connect: %s", err.Error())
+				}
+				h.SetAlive(true)
+				s.hosts = append(s.hosts, h)
+			}
+
+			input := wrpc.OperationInput{
+				Ops: map[string]wrpc.Operation{
+					"f": {OpType: "file_exists", PathArg: "{{.Host.Label.path}}", Severity: tc.severity},
+				},
+				Strategy: tc.strategy,
+			}
+			s.runAsyncOperation("h", input)
+
+			out := s.getOpBuffer("h")
+			if out.Aborted != tc.wantAborted {
+				tt.Errorf("got aborted %v (%s), want %v", out.Aborted, out.AbortReason, tc.wantAborted)
+			}
+			for i, h := range s.hosts {
+				res := out.Results[wrpc.ServerIdentifier(h.Endpoint)]["f"]
+				if res.Status != tc.wantStatus[i] {
+					tt.Errorf("host %d: got %s (%s), want %s", i, res.Status, res.Err, tc.wantStatus[i])
+				}
+				if tc.wantAborted && res.Status == wrpc.StatusUnknown && !strings.HasPrefix(res.Err, "Not run") {
+					tt.Errorf("host %d: got error %q, want not run", i, res.Err)
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package pool
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	t "github.com/disq/werify/cmd/werifyd/types"
 )
@@ -16,6 +17,14 @@ type Pool struct {
 	wg         *sync.WaitGroup
 }
 
+// runningWorkers and busyWorkers are the number of workers across all pools, for stats
+var runningWorkers, busyWorkers int64
+
+// Stats returns the number of running and busy (processing data) workers across all pools
+func Stats() (running, busy int64) {
+	return atomic.LoadInt64(&runningWorkers), atomic.LoadInt64(&busyWorkers)
+}
+
 // PoolCallback is the data process callback
 type PoolCallback func(t.PoolData)
 
@@ -47,13 +56,18 @@ func (p *Pool) Wait() {
 func (p *Pool) worker(callback PoolCallback) {
 	defer p.wg.Done()
 
+	atomic.AddInt64(&runningWorkers, 1)
+	defer atomic.AddInt64(&runningWorkers, -1)
+
 	for {
 		select {
 		case data, ok := <-p.in:
 			if !ok { // channel closed
 				return
 			}
+			atomic.AddInt64(&busyWorkers, 1)
 			callback(data)
+			atomic.AddInt64(&busyWorkers, -1)
 		case <-p.ctx.Done():
 			return
 		}
@@ -12,6 +12,9 @@ import (
 	wrpc "github.com/disq/werify/rpc"
 )
 
+// errEnvMismatch is returned from rpc handlers if the env tag of the caller doesn't match ours
+var errEnvMismatch = errors.New("env mismatch")
+
 // Server is our main struct
 type Server struct {
 	context context.Context
@@ -20,8 +23,20 @@ type Server struct {
 	// identifier is for tracing operation results back to the coordinator
 	identifier wrpc.ServerIdentifier
 
+	// hostname is reported in Hello and used in templates
+	hostname string
+
 	numWorkers int
 
+	// pluginDir is the directory of exec_plugin executables, empty if disabled
+	pluginDir string
+
+	// allowCommands enables the command check type
+	allowCommands bool
+
+	// capabilities is the list of optional features, reported in Hello
+	capabilities []string
+
 	// hosts is the list of hosts
 	hosts  []*t.Host
 	hostMu sync.RWMutex
@@ -32,6 +47,8 @@ type Server struct {
 	nextOpHandle uint64
 
 	forceHealthcheck chan struct{}
+
+	metrics *metrics
 }
 
 func (s *Server) getHostByEndpoint(endpoint wrpc.Endpoint, lock bool) (index int, host *t.Host) {
@@ -50,12 +67,14 @@ func (s *Server) getHostByEndpoint(endpoint wrpc.Endpoint, lock bool) (index int
 }
 
 // rpcMiddleware is poor man's net/rpc middleware for checking compatibility (? not sure it'll be enough) and env tag
-func (s *Server) rpcMiddleware(input *wrpc.CommonInput, callback func() error) error {
+func (s *Server) rpcMiddleware(method string, input *wrpc.CommonInput, callback func() error) (err error) {
+	defer s.metrics.observeRPC(method, time.Now(), &err)
+
 	if input == nil {
 		return errors.New("commonInput nil pointer")
 	}
 	if input.EnvTag != s.env {
-		return errors.New("env mismatch")
+		return errEnvMismatch
 	}
 
 	return callback()
@@ -63,7 +82,7 @@ func (s *Server) rpcMiddleware(input *wrpc.CommonInput, callback func() error) e
 
 // AddHost is the rpc handler to add a host to our host list
 func (s *Server) AddHost(input wrpc.AddHostInput, output *wrpc.AddHostOutput) error {
-	return s.rpcMiddleware(&input.CommonInput, func() error {
+	return s.rpcMiddleware("AddHost", &input.CommonInput, func() error {
 		ep := wrpc.NewEndpoint(string(input.Endpoint), werify.DefaultPort)
 
 		i, _ := s.getHostByEndpoint(ep, true)
@@ -80,7 +99,7 @@ func (s *Server) AddHost(input wrpc.AddHostInput, output *wrpc.AddHostOutput) er
 			IsAlive:  false,
 		}
 
-		err := s.setIdentifier(h)
+		err := s.connect(h)
 		if err != nil {
 			return err
 		}
@@ -101,7 +120,7 @@ func (s *Server) AddHost(input wrpc.AddHostInput, output *wrpc.AddHostOutput) er
 
 // RemoveHost is the rpc handler to remove a host from our host list
 func (s *Server) RemoveHost(input wrpc.RemoveHostInput, output *wrpc.RemoveHostOutput) error {
-	return s.rpcMiddleware(&input.CommonInput, func() error {
+	return s.rpcMiddleware("RemoveHost", &input.CommonInput, func() error {
 		s.hostMu.Lock()
 		defer s.hostMu.Unlock()
 
@@ -117,6 +136,7 @@ func (s *Server) RemoveHost(input wrpc.RemoveHostInput, output *wrpc.RemoveHostO
 		if h.Conn != nil {
 			h.Conn.Close()
 		}
+		s.metrics.forgetHost(h.Endpoint)
 
 		output.Ok = true
 		return nil
@@ -125,17 +145,33 @@ func (s *Server) RemoveHost(input wrpc.RemoveHostInput, output *wrpc.RemoveHostO
 
 // ListHost is the rpc handler to list our hosts
 func (s *Server) ListHost(input wrpc.ListHostsInput, output *wrpc.ListHostsOutput) error {
-	return s.rpcMiddleware(&input.CommonInput, func() error {
+	return s.rpcMiddleware("ListHost", &input.CommonInput, func() error {
 		s.hostMu.RLock()
 		defer s.hostMu.RUnlock()
 
+		output.Info = make(map[wrpc.Endpoint]wrpc.HostInfo)
+		output.Labels = make(map[wrpc.Endpoint]map[string]string)
+
 		for _, h := range s.hosts {
 			h.Lock()
+			listed := false
 			if input.ListActive && h.IsAlive {
 				output.ActiveHosts = append(output.ActiveHosts, h.Endpoint)
+				listed = true
 			}
 			if input.ListInactive && !h.IsAlive {
 				output.InactiveHosts = append(output.InactiveHosts, h.Endpoint)
+				listed = true
+			}
+			if listed && h.Info.ProtoVersion != "" {
+				output.Info[h.Endpoint] = h.Info
+			}
+			if listed && len(h.Labels) > 0 {
+				labels := make(map[string]string, len(h.Labels))
+				for k, v := range h.Labels {
+					labels[k] = v
+				}
+				output.Labels[h.Endpoint] = labels
 			}
 			h.Unlock()
 		}
@@ -144,17 +180,67 @@ func (s *Server) ListHost(input wrpc.ListHostsInput, output *wrpc.ListHostsOutpu
 	})
 }
 
+// LabelHost is the rpc handler to set or remove labels of a host
+func (s *Server) LabelHost(input wrpc.LabelHostInput, output *wrpc.LabelHostOutput) error {
+	return s.rpcMiddleware("LabelHost", &input.CommonInput, func() error {
+		e := wrpc.NewEndpoint(string(input.Endpoint), werify.DefaultPort)
+
+		_, h := s.getHostByEndpoint(e, true)
+		if h == nil {
+			return errors.New("endpoint does not exist in host list")
+		}
+
+		h.Lock()
+		defer h.Unlock()
+
+		for k, v := range input.Labels {
+			if k == "" {
+				return errors.New("empty label name")
+			}
+			if v == "" {
+				delete(h.Labels, k)
+				continue
+			}
+			if h.Labels == nil {
+				h.Labels = make(map[string]string)
+			}
+			h.Labels[k] = v
+		}
+
+		output.Ok = true
+		return nil
+	})
+}
+
 // HealthCheck is a no-op rpc handler for health-check purposes
 func (s *Server) HealthCheck(input wrpc.HealthCheckInput, output *wrpc.HealthCheckOutput) error {
-	return s.rpcMiddleware(&input.CommonInput, func() error {
+	return s.rpcMiddleware("HealthCheck", &input.CommonInput, func() error {
 		output.Ok = true
 		return nil
 	})
 }
 
+// helloService is registered as wrpc.HelloService, exposing only the Hello handler
+type helloService struct {
+	s *Server
+}
+
+// Hello is the rpc handler to exchange version information and supported features
+func (hs *helloService) Hello(input wrpc.HelloInput, output *wrpc.HelloOutput) error {
+	return hs.s.rpcMiddleware("Hello", &input.CommonInput, func() error {
+		output.ProtoVersion = wrpc.ProtoVersion
+		output.BuildVersion = werify.Version
+		output.CheckTypes = hs.s.supportedCheckTypes()
+		output.DisabledCheckTypes = hs.s.disabledCheckTypes()
+		output.Capabilities = hs.s.capabilities
+		output.Hostname = hs.s.hostname
+		return nil
+	})
+}
+
 // SetIdentifier is the rpc handler to set our endpoint identifier
 func (s *Server) SetIdentifier(input wrpc.SetIdentifierInput, output *wrpc.SetIdentifierOutput) error {
-	return s.rpcMiddleware(&input.CommonInput, func() error {
+	return s.rpcMiddleware("SetIdentifier", &input.CommonInput, func() error {
 		if s.identifier == "" {
 			s.identifier = input.Identifier
 			output.Ok = true
@@ -171,7 +257,7 @@ func (s *Server) SetIdentifier(input wrpc.SetIdentifierInput, output *wrpc.SetId
 
 // Refresh is the rpc handler to force a health-check
 func (s *Server) Refresh(input wrpc.RefreshInput, output *wrpc.RefreshOutput) error {
-	return s.rpcMiddleware(&input.CommonInput, func() error {
+	return s.rpcMiddleware("Refresh", &input.CommonInput, func() error {
 		// Don't block if we already have another one queued up
 		go func() {
 			s.forceHealthcheck <- struct{}{}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/rpc"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	wrpc "github.com/disq/werify/rpc"
@@ -15,7 +16,16 @@ type Host struct {
 	Endpoint               wrpc.Endpoint
 	Added                  time.Time
 	LastHealthCheckAttempt *time.Time
-	IsAlive                bool
+
+	// IsAlive should be set with SetAlive, so that Alive can read it without locking the Host
+	IsAlive bool
+	alive   uint32
+
+	// Info is filled in from the Hello call, zero-value if the host doesn't support it
+	Info wrpc.HostInfo
+
+	// Labels are set by the user, and used in templates
+	Labels map[string]string
 
 	sync.Mutex
 	Conn *rpc.Client
@@ -26,6 +36,44 @@ func (h *Host) String() string {
 	return fmt.Sprintf("Host[%s alive=%t]", h.Endpoint, h.IsAlive)
 }
 
+// SetAlive sets IsAlive. Host should be locked.
+func (h *Host) SetAlive(v bool) {
+	h.IsAlive = v
+	var a uint32
+	if v {
+		a = 1
+	}
+	atomic.StoreUint32(&h.alive, a)
+}
+
+// Alive returns the IsAlive value set with SetAlive. It doesn't need the lock, so it doesn't wait for ongoing RPC calls to the host.
+func (h *Host) Alive() bool {
+	return atomic.LoadUint32(&h.alive) == 1
+}
+
+// SupportsCheckType returns true if the host reported the check type as supported, or if we don't know.
+func (h *Host) SupportsCheckType(opType wrpc.OperationType) bool {
+	if h.Info.CheckTypes == nil {
+		return true
+	}
+	for _, t := range h.Info.CheckTypes {
+		if t == opType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCheckTypeDisabled returns true if the host reported the check type as supported, but not enabled
+func (h *Host) IsCheckTypeDisabled(opType wrpc.OperationType) bool {
+	for _, t := range h.Info.DisabledCheckTypes {
+		if t == opType {
+			return true
+		}
+	}
+	return false
+}
+
 // GetName satisfies the PoolData interface, returning zero-value
 func (h *Host) GetName() string {
 	return ""
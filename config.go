@@ -5,3 +5,6 @@ const DefaultPort = 30035
 
 // DefaultEnv is the default environment
 const DefaultEnv = "dev"
+
+// Version is the build version, set using -ldflags "-X github.com/disq/werify.Version=..."
+var Version = "dev"
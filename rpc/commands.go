@@ -11,21 +11,26 @@ type CommandConfig struct {
 	// Description is the cli help string
 	Description string
 
-	// RpcMethod is the method to call
+	// RpcMethod is the method to call, empty if the command doesn't connect to werifyd
 	RpcMethod string
 }
 
 // RunOperationRpcCommand is the name of the Run Operation RPC command
 const RunOperationRpcCommand = "RunOperation"
 
+// PlanOperationRpcCommand is the name of the Plan Operation RPC command, used by the operation command with --plan
+const PlanOperationRpcCommand = "PlanOperation"
+
 // Commands is the map of all cli commands. Key is the command name in cli.
 var Commands = map[string]CommandConfig{
 	"add":          {1, 1, "Adds a host to werifyd", "AddHost"},
 	"del":          {2, 1, "Removes a host from werifyd", "RemoveHost"},
-	"list":         {3, 0, "Lists hosts in werifyd", "ListHost"},
-	"listactive":   {4, 0, "Lists active hosts in werifyd", "ListHost"},
-	"listinactive": {5, 0, "Lists inactive hosts in werifyd", "ListHost"},
-	"operation":    {6, 1, "Runs operations from file on werifyd", RunOperationRpcCommand},
-	"get":          {7, 1, "Get status of operation with handle", "OperationStatusCheck"},
-	"refresh":      {8, 0, "Start health check on all hosts", "Refresh"},
+	"label":        {3, 2, "Sets a label on a host as key=value, or removes it with key=", "LabelHost"},
+	"list":         {4, 0, "Lists hosts in werifyd", "ListHost"},
+	"listactive":   {5, 0, "Lists active hosts in werifyd", "ListHost"},
+	"listinactive": {6, 0, "Lists inactive hosts in werifyd", "ListHost"},
+	"operation":    {7, 1, "Runs operations from file on werifyd (--plan to only report what would run where, --vars to use a vars file)", RunOperationRpcCommand},
+	"get":          {8, 1, "Get status of operation with handle", "OperationStatusCheck"},
+	"refresh":      {9, 0, "Start health check on all hosts", "Refresh"},
+	"validate":     {10, 1, "Validates operations file locally", ""},
 }
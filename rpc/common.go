@@ -5,7 +5,7 @@ const ProtoVersion = "werify.v1"
 
 // CommonInput is included in all RPC inputs
 type CommonInput struct {
-	EnvTag string
+	EnvTag string `json:"env_tag"`
 }
 
 // BuildMethod prepends the ProtoVersion to the rpc method name
@@ -7,5 +7,5 @@ type HealthCheckInput struct {
 
 // HealthCheckOutput is the output struct for the health-check functionality
 type HealthCheckOutput struct {
-	Ok bool
+	Ok bool `json:"ok"`
 }
@@ -0,0 +1,40 @@
+package rpc
+
+// HelloService is the version-independent RPC service name. It only serves the Hello method, so that peers
+// with mismatching ProtoVersions can still find out about each other.
+const HelloService = "werify"
+
+// HelloRpcCommand is the name of the Hello RPC command, under HelloService
+const HelloRpcCommand = "Hello"
+
+// HelloInput is the input struct for the hello functionality
+type HelloInput struct {
+	CommonInput
+
+	// ProtoVersion and BuildVersion are of the caller
+	ProtoVersion string `json:"proto_version"`
+	BuildVersion string `json:"build_version"`
+}
+
+// HelloOutput is the output struct for the hello functionality
+type HelloOutput struct {
+	ProtoVersion string `json:"proto_version"`
+	BuildVersion string `json:"build_version"`
+
+	// CheckTypes is the list of supported check (operation) types
+	CheckTypes []OperationType `json:"check_types"`
+
+	// DisabledCheckTypes is the list of check types which are supported, but not enabled in the server config
+	DisabledCheckTypes []OperationType `json:"disabled_check_types,omitempty"`
+
+	// Capabilities is the list of optional features enabled on the server
+	Capabilities []string `json:"capabilities"`
+
+	// Hostname is the hostname of the server, used in templates
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// BuildHelloMethod returns the full method name for the Hello RPC command
+func BuildHelloMethod() string {
+	return HelloService + "." + HelloRpcCommand
+}
@@ -4,36 +4,66 @@ package rpc
 // AddHostInput is the input struct for the add host functionality
 type AddHostInput struct {
 	CommonInput
-	Endpoint Endpoint
+	Endpoint Endpoint `json:"endpoint"`
 }
 
 // AddHostOutput is the output struct for the add host functionality
 type AddHostOutput struct {
-	Ok bool
+	Ok bool `json:"ok"`
 }
 
 // RemoveHostInput is the input struct for the remove host functionality
 type RemoveHostInput struct {
 	CommonInput
-	Endpoint Endpoint
+	Endpoint Endpoint `json:"endpoint"`
 }
 
 // RemoveHostOutput is the output struct for the remove host functionality
 type RemoveHostOutput struct {
-	Ok bool
+	Ok bool `json:"ok"`
 }
 
 // ListHostsInput is the input struct for the list hosts functionality
 type ListHostsInput struct {
 	CommonInput
-	ListActive   bool
-	ListInactive bool
+	ListActive   bool `json:"list_active"`
+	ListInactive bool `json:"list_inactive"`
 }
 
 // ListHostsOutput is the output struct for the list hosts functionality
 type ListHostsOutput struct {
-	ActiveHosts   []Endpoint
-	InactiveHosts []Endpoint
+	ActiveHosts   []Endpoint `json:"active_hosts"`
+	InactiveHosts []Endpoint `json:"inactive_hosts"`
+
+	// Info is the version information of listed hosts, if known
+	Info map[Endpoint]HostInfo `json:"info,omitempty"`
+
+	// Labels are the labels of listed hosts, if any
+	Labels map[Endpoint]map[string]string `json:"labels,omitempty"`
+}
+
+// HostInfo is the version information of a host, as reported by its Hello call
+type HostInfo struct {
+	ProtoVersion       string          `json:"proto_version"`
+	BuildVersion       string          `json:"build_version"`
+	CheckTypes         []OperationType `json:"check_types"`
+	DisabledCheckTypes []OperationType `json:"disabled_check_types,omitempty"`
+	Capabilities       []string        `json:"capabilities"`
+	Hostname           string          `json:"hostname,omitempty"`
+}
+
+// LabelHostInput is the input struct for the label host functionality
+type LabelHostInput struct {
+	CommonInput
+	Endpoint Endpoint `json:"endpoint"`
+
+	// Labels are set on the host, labels with empty values are removed
+	Labels map[string]string `json:"labels"`
+}
+
+// LabelHostOutput is the output struct for the label host functionality
+type LabelHostOutput struct {
+	Ok bool `json:"ok"`
 }
 
 // RefreshInput is the input struct for refresh hosts/start healthcheck functionality
@@ -43,5 +73,5 @@ type RefreshInput struct {
 
 // RefreshOutput is the output struct for refresh hosts/start healthcheck functionality
 type RefreshOutput struct {
-	Ok bool
+	Ok bool `json:"ok"`
 }
@@ -6,10 +6,10 @@ type ServerIdentifier string
 // SetIdentifierInput is the input struct for the set identifier functionality
 type SetIdentifierInput struct {
 	CommonInput
-	Identifier ServerIdentifier
+	Identifier ServerIdentifier `json:"identifier"`
 }
 
 // SetIdentifierOutput is the output struct for the set identifier functionality
 type SetIdentifierOutput struct {
-	Ok bool
+	Ok bool `json:"ok"`
 }
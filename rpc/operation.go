@@ -1,6 +1,9 @@
 package rpc
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // OperationType is the type of the operation
 type OperationType string
@@ -13,13 +16,93 @@ type Operation struct {
 	OpType   OperationType     `json:"type"`
 	PathArg  OperationArgument `json:"path,omitempty"`
 	CheckArg OperationArgument `json:"check,omitempty"`
+
+	// Params are named parameters of the check. The "path" and "check" params are aliases for PathArg and CheckArg, the rest are merged into the check options.
+	Params map[string]interface{} `json:"params,omitempty"`
+
+	// Description explains why the check exists, it's copied to the result
+	Description string `json:"description,omitempty"`
+
+	// Severity is the Status to report if a pass/fail check fails, either WARNING or CRITICAL (the default)
+	Severity Status `json:"severity,omitempty"`
+
+	// Thresholds are used by numeric checks to determine the Status
+	Thresholds
+
+	// Timeout is the maximum duration of the check, in time.ParseDuration format. The host default is used if empty.
+	Timeout string `json:"timeout,omitempty"`
+
+	// Retries is the number of times to re-run the check if it doesn't succeed
+	Retries int `json:"retries,omitempty"`
+
+	// RetryInterval is the duration to wait between attempts, in time.ParseDuration format. The host default is used if empty.
+	RetryInterval string `json:"retry_interval,omitempty"`
+}
+
+// GetTimeout parses the Timeout of the operation, returning def if it's not set
+func (o Operation) GetTimeout(def time.Duration) (time.Duration, error) {
+	if o.Timeout == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(o.Timeout)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("Invalid timeout: %s", o.Timeout)
+	}
+	return d, nil
+}
+
+// GetRetryInterval parses the RetryInterval of the operation, returning def if it's not set
+func (o Operation) GetRetryInterval(def time.Duration) (time.Duration, error) {
+	if o.RetryInterval == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(o.RetryInterval)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("Invalid retry interval: %s", o.RetryInterval)
+	}
+	return d, nil
 }
 
 // OperationResult is a result of a single operation
 type OperationResult struct {
-	Success bool
+	Success bool `json:"success"`
 	// Err is the error value as a primitive
-	Err string
+	Err string `json:"error,omitempty"`
+
+	// Status is the state reported by the check, if it has more than success/failure
+	Status Status `json:"status,omitempty"`
+
+	// Output is the (possibly truncated) output or observed values from the check, if any
+	Output string `json:"output,omitempty"`
+
+	// PerfData is the Nagios-style performance data from the check, if any
+	PerfData string `json:"perfdata,omitempty"`
+
+	// Attempts is the number of times the check was run, if it was retried
+	Attempts int `json:"attempts,omitempty"`
+
+	// Description is copied from the Operation
+	Description string `json:"description,omitempty"`
+}
+
+// GetStatus returns the Status of the result, deriving it for results from hosts which don't report one
+func (r OperationResult) GetStatus() Status {
+	switch {
+	case r.Status != "":
+		return r.Status
+	case r.Err != "":
+		return StatusUnknown
+	case r.Success:
+		return StatusOK
+	default:
+		return StatusCritical
+	}
 }
 
 // OperationInput is the input struct for the operation functionality
@@ -28,25 +111,57 @@ type OperationInput struct {
 
 	// Forward determines if we are running these operations on the currect context or forwarding them down to other hosts.
 	// This also makes the current call async, in OperationOutput only Id will be returned.
-	Forward bool
+	Forward bool `json:"forward"`
 
 	// Ops is a map of operations, map key is the given unique name
-	Ops map[string]Operation
+	Ops map[string]Operation `json:"ops"`
+
+	// Vars are the values available to templates in Ops as {{.Vars.name}}
+	Vars map[string]interface{} `json:"vars,omitempty"`
+
+	// Rendered is set by the coordinator if the templates in Ops are already rendered for the host
+	Rendered bool `json:"rendered,omitempty"`
+
+	// Strategy is how the operation is rolled out to the hosts, if Forward is set
+	Strategy Strategy `json:"strategy"`
+
+	// Timeout is the maximum duration of the whole call, in time.ParseDuration format. Checks which haven't finished by then are reported as timed out.
+	// It's set by the coordinator when forwarding, and unlimited if empty.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// GetTimeout parses the Timeout of the input, returning zero if it's not set
+func (i OperationInput) GetTimeout() (time.Duration, error) {
+	if i.Timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(i.Timeout)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("Invalid timeout: %s", i.Timeout)
+	}
+	return d, nil
 }
 
 // OperationOutput is the output struct for the operation functionality
 type OperationOutput struct {
 	// Handle is a unique id to check the results using OperationStatusCheckInput
-	Handle string
+	Handle string `json:"handle"`
 
 	// Results is a map of results per given unique name per server identifier
-	Results map[ServerIdentifier]map[string]OperationResult
+	Results map[ServerIdentifier]map[string]OperationResult `json:"results"`
 
 	// StartedAt is the start time
-	StartedAt time.Time
+	StartedAt time.Time `json:"started_at"`
 
 	// EndedAt shows if the operation is still running or ended
-	EndedAt *time.Time
+	EndedAt *time.Time `json:"ended_at"`
+
+	// Aborted is set if the operation was stopped before running on all hosts, with the reason in AbortReason
+	Aborted     bool   `json:"aborted,omitempty"`
+	AbortReason string `json:"abort_reason,omitempty"`
 }
 
 // OperationStatusCheckInput is the input struct to check status of an operation
@@ -54,8 +169,23 @@ type OperationStatusCheckInput struct {
 	CommonInput
 
 	// Handle is the unique id of the operation to check results for
-	Handle string
+	Handle string `json:"handle"`
 }
 
 // OperationStatusCheckOutput is the output struct for the operation functionality
 type OperationStatusCheckOutput OperationOutput
+
+// PlannedOperation is what would be done with a single operation on a host
+type PlannedOperation struct {
+	// Run is true if the operation would be run on the host
+	Run bool `json:"run"`
+
+	// Reason is why the operation wouldn't run, or a caveat if it would
+	Reason string `json:"reason,omitempty"`
+}
+
+// PlanOutput is the output struct for the plan functionality, which takes an OperationInput
+type PlanOutput struct {
+	// Hosts is a map of planned operations per given unique name per server identifier
+	Hosts map[ServerIdentifier]map[string]PlannedOperation `json:"hosts"`
+}
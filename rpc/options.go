@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fields splits the argument into whitespace-separated fields, shell-style. Single quotes, double quotes and backslash escapes are supported.
+func (a OperationArgument) Fields() ([]string, error) {
+	var fields []string
+	var cur strings.Builder
+	inField := false
+
+	s := string(a)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated quote in %s", s)
+			}
+			cur.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inField = true
+
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\') {
+					i++
+				}
+				cur.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("Unterminated quote in %s", s)
+			}
+			inField = true
+
+		case c == '\\' && i+1 < len(s):
+			i++
+			cur.WriteByte(s[i])
+			inField = true
+
+		default:
+			cur.WriteByte(c)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+
+	return fields, nil
+}
+
+// Options parses the argument as whitespace-separated key=value pairs. Values can be quoted to contain whitespace, see Fields.
+func (a OperationArgument) Options() (map[string]string, error) {
+	fields, err := a.Fields()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := make(map[string]string, len(fields))
+	for _, f := range fields {
+		eq := strings.Index(f, "=")
+		if eq < 1 {
+			return nil, fmt.Errorf("Invalid option, expected key=value: %s", f)
+		}
+		opts[f[:eq]] = f[eq+1:]
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,167 @@
+package rpc
+
+import (
+	"encoding/gob"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	// Params can hold JSON-decoded lists and objects, which gob needs to know about
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// Path returns the "path" param, or PathArg if it's not set (or invalid, which Validate and Normalize report)
+func (o Operation) Path() string {
+	if v, ok, err := o.argParam("path"); ok && err == nil {
+		return v
+	}
+	return string(o.PathArg)
+}
+
+// Check returns the "check" param, or CheckArg if it's not set (or invalid, which Validate and Normalize report)
+func (o Operation) Check() OperationArgument {
+	if v, ok, err := o.argParam("check"); ok && err == nil {
+		return OperationArgument(v)
+	}
+	return o.CheckArg
+}
+
+// Normalize returns a copy of the operation with the path and check params moved to PathArg and CheckArg, for hosts which don't support params.
+// Returns an error if either of them is invalid.
+func (o Operation) Normalize() (Operation, error) {
+	path, pathFound, err := o.argParam("path")
+	if err != nil {
+		return o, err
+	}
+	check, checkFound, err := o.argParam("check")
+	if err != nil {
+		return o, err
+	}
+	if !pathFound && !checkFound {
+		return o, nil
+	}
+
+	params := make(map[string]interface{}, len(o.Params))
+	for k, v := range o.Params {
+		if k != "path" && k != "check" {
+			params[k] = v
+		}
+	}
+	o.Params = params
+
+	if pathFound {
+		o.PathArg = OperationArgument(path)
+	}
+	if checkFound {
+		o.CheckArg = OperationArgument(check)
+	}
+	return o, nil
+}
+
+// argParam returns the path or check param, which should be a single value
+func (o Operation) argParam(name string) (value string, found bool, err error) {
+	switch o.Params[name].(type) {
+	case []interface{}, []string, map[string]interface{}:
+		return "", true, fmt.Errorf("Invalid param %s: should be a single value", name)
+	}
+	return o.Param(name)
+}
+
+// Param returns the named param as a string. Lists are returned comma-separated.
+func (o Operation) Param(name string) (value string, found bool, err error) {
+	v, found := o.Params[name]
+	if !found {
+		return "", false, nil
+	}
+	value, err = paramString(v)
+	if err != nil {
+		return "", true, fmt.Errorf("Invalid param %s: %s", name, err.Error())
+	}
+	return value, true, nil
+}
+
+// ListParam returns the named param as a list. A single value is returned as a list of one.
+func (o Operation) ListParam(name string) (list []string, found bool, err error) {
+	v, found := o.Params[name]
+	if !found {
+		return nil, false, nil
+	}
+
+	var items []interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		items = v
+	case []string:
+		return v, true, nil
+	default:
+		items = []interface{}{v}
+	}
+
+	list = make([]string, 0, len(items))
+	for _, item := range items {
+		if _, isList := item.([]interface{}); isList {
+			return nil, true, fmt.Errorf("Invalid param %s: nested lists are not supported", name)
+		}
+		s, err := paramString(item)
+		if err != nil {
+			return nil, true, fmt.Errorf("Invalid param %s: %s", name, err.Error())
+		}
+		list = append(list, s)
+	}
+	return list, true, nil
+}
+
+// Options returns the options in the check argument (see OperationArgument.Options) merged with the params, params taking precedence.
+// The path and check params aren't included.
+func (o Operation) Options() (map[string]string, error) {
+	opts, err := o.Check().Options()
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range o.Params {
+		if k == "path" || k == "check" {
+			continue
+		}
+		v, _, err := o.Param(k)
+		if err != nil {
+			return nil, err
+		}
+		opts[k] = v
+	}
+	return opts, nil
+}
+
+// paramString converts a param value to the string used in options
+func paramString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool, int, int64, uint64:
+		return fmt.Sprint(v), nil
+	case []string:
+		return strings.Join(v, ","), nil
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			if _, isList := item.([]interface{}); isList {
+				return "", fmt.Errorf("nested lists are not supported")
+			}
+			s, err := paramString(item)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", v)
+	}
+}
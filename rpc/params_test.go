@@ -0,0 +1,191 @@
+package rpc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParam(t *testing.T) {
+	o := Operation{Params: map[string]interface{}{
+		"user":   "www-data",
+		"min":    float64(2),
+		"ratio":  0.5,
+		"follow": true,
+		"empty":  nil,
+		"ports":  []interface{}{float64(80), "443"},
+		"names":  []string{"a", "b"},
+		"nested": []interface{}{[]interface{}{"a"}},
+		"object": map[string]interface{}{"a": "b"},
+	}}
+
+	tests := []struct {
+		name    string
+		want    string
+		found   bool
+		wantErr bool
+	}{
+		{"user", "www-data", true, false},
+		{"min", "2", true, false},
+		{"ratio", "0.5", true, false},
+		{"follow", "true", true, false},
+		{"empty", "", true, false},
+		{"ports", "80,443", true, false},
+		{"names", "a,b", true, false},
+		{"nested", "", true, true},
+		{"object", "", true, true},
+		{"missing", "", false, false},
+	}
+
+	for _, tt := range tests {
+		got, found, err := o.Param(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want || found != tt.found {
+			t.Errorf("%s: got %q (found %v), want %q (found %v)", tt.name, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestListParam(t *testing.T) {
+	o := Operation{Params: map[string]interface{}{
+		"single": "a b",
+		"number": float64(3),
+		"list":   []interface{}{"a", float64(1), true},
+		"names":  []string{"a", "b"},
+		"nested": []interface{}{"a", []interface{}{"b"}},
+		"object": []interface{}{map[string]interface{}{"a": "b"}},
+	}}
+
+	tests := []struct {
+		name    string
+		want    []string
+		found   bool
+		wantErr bool
+	}{
+		{"single", []string{"a b"}, true, false},
+		{"number", []string{"3"}, true, false},
+		{"list", []string{"a", "1", "true"}, true, false},
+		{"names", []string{"a", "b"}, true, false},
+		{"nested", nil, true, true},
+		{"object", nil, true, true},
+		{"missing", nil, false, false},
+	}
+
+	for _, tt := range tests {
+		got, found, err := o.ListParam(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) || found != tt.found {
+			t.Errorf("%s: got %q (found %v), want %q (found %v)", tt.name, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	o := Operation{
+		CheckArg: `user=root min=1 cmdline="^nginx: master"`,
+		Params: map[string]interface{}{
+			"min":   float64(2),
+			"max":   float64(4),
+			"path":  "/ignored",
+			"check": "user=ignored",
+		},
+	}
+
+	got, err := o.Options()
+	if err != nil {
+		t.Fatalf("Options: %s", err.Error())
+	}
+
+	// The check param would replace CheckArg, but it's not an option itself
+	want := map[string]string{"user": "ignored", "min": "2", "max": "4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	o.Params = map[string]interface{}{"min": float64(2)}
+	got, err = o.Options()
+	if err != nil {
+		t.Fatalf("Options: %s", err.Error())
+	}
+	want = map[string]string{"user": "root", "min": "2", "cmdline": "^nginx: master"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("params over CheckArg: got %v, want %v", got, want)
+	}
+
+	o.Params = map[string]interface{}{"max": []interface{}{[]interface{}{"a"}}}
+	if _, err := o.Options(); err == nil {
+		t.Errorf("nested list param: expected error")
+	}
+
+	o = Operation{CheckArg: "user"}
+	if _, err := o.Options(); err == nil {
+		t.Errorf("option without value: expected error")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	o := Operation{
+		OpType:   "file_exists",
+		PathArg:  "/old",
+		CheckArg: "old",
+		Params:   map[string]interface{}{"path": "/etc/passwd", "check": "user=root", "min": float64(1)},
+	}
+
+	n, err := o.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize: %s", err.Error())
+	}
+	if n.PathArg != "/etc/passwd" || n.CheckArg != "user=root" {
+		t.Errorf("got path %q check %q", n.PathArg, n.CheckArg)
+	}
+	if !reflect.DeepEqual(n.Params, map[string]interface{}{"min": float64(1)}) {
+		t.Errorf("got params %v", n.Params)
+	}
+	if _, found := o.Params["path"]; !found {
+		t.Errorf("Normalize modified the original params")
+	}
+
+	// Without path and check params, nothing changes
+	o = Operation{PathArg: "/a", Params: map[string]interface{}{"min": float64(1)}}
+	n, err = o.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize: %s", err.Error())
+	}
+	if !reflect.DeepEqual(n, o) {
+		t.Errorf("got %+v, want %+v", n, o)
+	}
+
+	// Only the path param set keeps CheckArg
+	o = Operation{CheckArg: "min=1", Params: map[string]interface{}{"path": "/a"}}
+	n, _ = o.Normalize()
+	if n.PathArg != "/a" || n.CheckArg != "min=1" {
+		t.Errorf("got path %q check %q", n.PathArg, n.CheckArg)
+	}
+
+	for _, v := range []interface{}{[]interface{}{"/a", "/b"}, []string{"/a"}, map[string]interface{}{"a": "b"}} {
+		o = Operation{Params: map[string]interface{}{"path": v}}
+		if _, err := o.Normalize(); err == nil {
+			t.Errorf("path param %v: expected error", v)
+		}
+		if got := o.Path(); got != "" {
+			t.Errorf("path param %v: Path() got %q", v, got)
+		}
+	}
+}
+
+func TestValidateArgParams(t *testing.T) {
+	o := Operation{
+		OpType: "file_exists",
+		Params: map[string]interface{}{"path": []interface{}{"/a", "/b"}},
+	}
+
+	errs := o.Validate()
+	if len(errs) != 1 || errs[0].Field != "params.path" {
+		t.Errorf("got %v, want a single params.path error", errs)
+	}
+}
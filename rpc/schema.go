@@ -0,0 +1,205 @@
+package rpc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CheckSchema describes the parameters of a check type, to validate operations before running them
+type CheckSchema struct {
+	// PathRequired is true if the check type needs the path parameter
+	PathRequired bool
+
+	// CheckRequired is true if the check type needs the check parameter
+	CheckRequired bool
+
+	// FreeformCheck is true if the check parameter isn't parsed as options
+	FreeformCheck bool
+
+	// Options are the known option names, given in the check parameter or in params
+	Options []string
+
+	// Required are the options which should all be supplied
+	Required []string
+
+	// AnyOf are the parameters (options, or "path" or "check") at least one of which should be supplied
+	AnyOf []string
+
+	// Thresholds is true if the check type uses the warning and critical thresholds
+	Thresholds bool
+}
+
+// CheckSchemas is the schema of each check type supported by werifyd
+var CheckSchemas = map[OperationType]CheckSchema{
+	"file_exists":       {PathRequired: true},
+	"file_contains":     {PathRequired: true, CheckRequired: true, FreeformCheck: true},
+	"process_running":   {FreeformCheck: true, AnyOf: []string{"path", "check"}},
+	"process":           {Options: []string{"cmdline", "user", "min", "max", "max_rss", "max_fds", "min_age", "max_age"}, AnyOf: []string{"cmdline", "user"}},
+	"mount":             {PathRequired: true, Options: []string{"fstype", "source", "options"}},
+	"sysctl":            {PathRequired: true, Options: []string{"equal", "min", "max"}, AnyOf: []string{"equal", "min", "max"}},
+	"kernel_module":     {Options: []string{"name", "loaded"}, Required: []string{"name"}},
+	"user_exists":       {Options: []string{"name", "present", "uid", "home", "shell"}, Required: []string{"name"}},
+	"group_member":      {Options: []string{"group", "user", "member"}, Required: []string{"group", "user"}},
+	"package_installed": {Options: []string{"name", "version"}, Required: []string{"name"}},
+	"interface":         {Options: []string{"name", "up", "mtu", "ipv4", "ipv6"}, Required: []string{"name"}},
+	"route":             {Options: []string{"to", "dev"}, Required: []string{"to"}},
+	"json_value":        {PathRequired: true, Options: []string{"key", "equal"}, Required: []string{"key"}},
+	"config_value":      {PathRequired: true, Options: []string{"key", "equal", "occurrence"}, Required: []string{"key"}},
+	"cert_expiry":       {Options: []string{"endpoint", "server_name"}, AnyOf: []string{"path", "endpoint"}, Thresholds: true},
+	"disk_usage":        {PathRequired: true, Options: []string{"metric"}, Thresholds: true},
+	"memory":            {Options: []string{"metric"}, Thresholds: true},
+	"load_average":      {Options: []string{"period", "per_cpu"}, Thresholds: true},
+	"exec_plugin":       {PathRequired: true, FreeformCheck: true, Options: []string{"args"}},
+	"command":           {Options: []string{"exit", "stdout", "stderr", "dir", "env", "shell", "args"}, AnyOf: []string{"path", "args"}},
+}
+
+// FieldError is a validation error of an Operation field
+type FieldError struct {
+	// Field is the JSON name of the field, ie. "type" or "params.user"
+	Field string
+
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Validate checks the operation against the schema of its check type. It doesn't know if a host has the check type enabled.
+func (o Operation) Validate() []FieldError {
+	var errs []FieldError
+	add := func(field, format string, a ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if o.Severity != "" && o.Severity != StatusWarning && o.Severity != StatusCritical {
+		add("severity", "Invalid severity %s, should be %s or %s", o.Severity, StatusWarning, StatusCritical)
+	}
+	if _, err := o.GetTimeout(0); err != nil {
+		add("timeout", "Invalid timeout: %s", err.Error())
+	}
+	if _, err := o.GetRetryInterval(0); err != nil {
+		add("retry_interval", "Invalid retry interval: %s", err.Error())
+	}
+	if o.Retries < 0 {
+		add("retries", "Invalid retries: %d", o.Retries)
+	}
+	errs = append(errs, o.validateTemplates()...)
+
+	invalidArg := make(map[string]bool)
+	for _, k := range []string{"path", "check"} {
+		if _, _, err := o.argParam(k); err != nil {
+			add("params."+k, "%s", err.Error())
+			invalidArg[k] = true
+		}
+	}
+
+	if o.OpType == "" {
+		add("type", "Missing type")
+		return errs
+	}
+	schema, ok := CheckSchemas[o.OpType]
+	if !ok {
+		add("type", "Unknown type %s", o.OpType)
+		return errs
+	}
+
+	if schema.PathRequired && o.Path() == "" && !invalidArg["path"] {
+		add("path", "Missing path")
+	}
+	if schema.CheckRequired && o.Check() == "" && !invalidArg["check"] {
+		add("check", "Missing check")
+	}
+	if !schema.Thresholds && (o.Warning != nil || o.Critical != nil) {
+		add("warning", "Thresholds are not used by %s", o.OpType)
+	}
+
+	// supplied has the options and the path/check parameters which aren't empty
+	supplied := make(map[string]bool)
+	if o.Path() != "" {
+		supplied["path"] = true
+	}
+	if o.Check() != "" {
+		supplied["check"] = true
+	}
+
+	known := make(map[string]bool, len(schema.Options))
+	for _, k := range schema.Options {
+		known[k] = true
+	}
+
+	// Options in a templated check argument are only known after rendering
+	templatedCheck := isTemplate(string(o.Check()))
+
+	var opts map[string]string
+	if schema.FreeformCheck || templatedCheck {
+		// Only params can have options
+		opts = make(map[string]string)
+		for k := range o.Params {
+			if k == "path" || k == "check" {
+				continue
+			}
+			v, _, err := o.Param(k)
+			if err != nil {
+				add("params."+k, "%s", err.Error())
+				continue
+			}
+			opts[k] = v
+		}
+	} else {
+		var err error
+		if opts, err = o.Options(); err != nil {
+			add("check", "%s", err.Error())
+			return errs
+		}
+	}
+
+	for _, k := range sortedOptionKeys(opts) {
+		if !known[k] {
+			add(o.optionField(k), "Unknown option %s for %s", k, o.OpType)
+			continue
+		}
+		if opts[k] != "" {
+			supplied[k] = true
+		}
+	}
+
+	if templatedCheck && !schema.FreeformCheck {
+		return errs
+	}
+
+	for _, k := range schema.Required {
+		if !supplied[k] {
+			add(o.optionField(k), "Missing option %s", k)
+		}
+	}
+	if len(schema.AnyOf) > 0 {
+		found := false
+		for _, k := range schema.AnyOf {
+			found = found || supplied[k]
+		}
+		if !found {
+			add("check", "At least one of %s should be supplied", strings.Join(schema.AnyOf, ", "))
+		}
+	}
+
+	return errs
+}
+
+// optionField returns the JSON field name the option is (or should be) in
+func (o Operation) optionField(name string) string {
+	if _, ok := o.Params[name]; ok {
+		return "params." + name
+	}
+	return "check"
+}
+
+func sortedOptionKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package rpc
+
+// Status is the Nagios-style state of a check result
+type Status string
+
+// Check result states
+const (
+	StatusOK       Status = "OK"
+	StatusWarning  Status = "WARNING"
+	StatusCritical Status = "CRITICAL"
+	StatusUnknown  Status = "UNKNOWN"
+)
+
+// StatusFromExitCode maps a Nagios plugin exit code to a Status
+func StatusFromExitCode(code int) Status {
+	switch code {
+	case 0:
+		return StatusOK
+	case 1:
+		return StatusWarning
+	case 2:
+		return StatusCritical
+	default:
+		return StatusUnknown
+	}
+}
+
+// statusRank orders the states by how bad they are, for WorseStatus
+var statusRank = map[Status]int{
+	StatusOK:       0,
+	StatusUnknown:  1,
+	StatusWarning:  2,
+	StatusCritical: 3,
+}
+
+// WorseStatus returns the worse of two states. CRITICAL is the worst, followed by WARNING, UNKNOWN and OK. Empty Status is ignored.
+func WorseStatus(a, b Status) Status {
+	if a == "" || statusRank[b] > statusRank[a] {
+		return b
+	}
+	return a
+}
+
+// ExitCode maps the Status to a Nagios plugin exit code
+func (s Status) ExitCode() int {
+	switch s {
+	case StatusOK:
+		return 0
+	case StatusWarning:
+		return 1
+	case StatusCritical:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Thresholds are the warning and critical levels for numeric checks
+type Thresholds struct {
+	Warning  *float64 `json:"warning,omitempty"`
+	Critical *float64 `json:"critical,omitempty"`
+}
+
+// Evaluate returns the Status of value against the thresholds. If higherIsWorse is false, values below the thresholds are considered bad.
+func (t Thresholds) Evaluate(value float64, higherIsWorse bool) Status {
+	exceeds := func(limit *float64) bool {
+		if limit == nil {
+			return false
+		}
+		if higherIsWorse {
+			return value >= *limit
+		}
+		return value <= *limit
+	}
+
+	if exceeds(t.Critical) {
+		return StatusCritical
+	}
+	if exceeds(t.Warning) {
+		return StatusWarning
+	}
+	return StatusOK
+}
@@ -0,0 +1,64 @@
+package rpc
+
+import "testing"
+
+func TestWorseStatus(t *testing.T) {
+	tests := []struct {
+		a, b, want Status
+	}{
+		{"", StatusOK, StatusOK},
+		{StatusOK, "", StatusOK},
+		{StatusOK, StatusUnknown, StatusUnknown},
+		{StatusUnknown, StatusWarning, StatusWarning},
+		{StatusWarning, StatusCritical, StatusCritical},
+		{StatusCritical, StatusWarning, StatusCritical},
+		{StatusWarning, StatusUnknown, StatusWarning},
+		{StatusUnknown, StatusOK, StatusUnknown},
+		{StatusCritical, StatusCritical, StatusCritical},
+	}
+
+	for _, tt := range tests {
+		if got := WorseStatus(tt.a, tt.b); got != tt.want {
+			t.Errorf("WorseStatus(%q, %q): got %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestStatusExitCode(t *testing.T) {
+	for code := 0; code <= 3; code++ {
+		if got := StatusFromExitCode(code).ExitCode(); got != code {
+			t.Errorf("%d: got %d", code, got)
+		}
+	}
+	if got := StatusFromExitCode(127); got != StatusUnknown {
+		t.Errorf("127: got %q, want %q", got, StatusUnknown)
+	}
+}
+
+func TestThresholdsEvaluate(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name          string
+		th            Thresholds
+		value         float64
+		higherIsWorse bool
+		want          Status
+	}{
+		{"no thresholds", Thresholds{}, 100, true, StatusOK},
+		{"below warning", Thresholds{Warning: f(80), Critical: f(90)}, 79, true, StatusOK},
+		{"at warning", Thresholds{Warning: f(80), Critical: f(90)}, 80, true, StatusWarning},
+		{"at critical", Thresholds{Warning: f(80), Critical: f(90)}, 90, true, StatusCritical},
+		{"critical only", Thresholds{Critical: f(90)}, 85, true, StatusOK},
+		{"warning only", Thresholds{Warning: f(80)}, 95, true, StatusWarning},
+		{"lower is worse, ok", Thresholds{Warning: f(14), Critical: f(3)}, 30, false, StatusOK},
+		{"lower is worse, warning", Thresholds{Warning: f(14), Critical: f(3)}, 14, false, StatusWarning},
+		{"lower is worse, critical", Thresholds{Warning: f(14), Critical: f(3)}, 2.5, false, StatusCritical},
+	}
+
+	for _, tt := range tests {
+		if got := tt.th.Evaluate(tt.value, tt.higherIsWorse); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Strategy is how a forwarded operation is rolled out to the hosts. The zero value runs the operation on all hosts at once.
+type Strategy struct {
+	// Canary is the number of hosts to run the operation on first, before the batches
+	Canary int `json:"canary,omitempty"`
+
+	// BatchSize is the number of hosts in each batch after the canary hosts. If it's zero (and BatchPercent is zero), all remaining hosts are in a single batch.
+	BatchSize int `json:"batch_size,omitempty"`
+
+	// BatchPercent is the batch size as a percentage of all hosts, if BatchSize is zero
+	BatchPercent float64 `json:"batch_percent,omitempty"`
+
+	// Pause is the duration to wait after the canary hosts and between batches, in time.ParseDuration format
+	Pause string `json:"pause,omitempty"`
+
+	// MaxFailurePercent aborts the operation if the percentage of failed hosts exceeds it, after the canary hosts or a batch.
+	// A host has failed if any of its checks is CRITICAL, or if the operation couldn't be run on it.
+	MaxFailurePercent *float64 `json:"max_failure_percent,omitempty"`
+}
+
+// Validate checks the strategy for invalid values
+func (s Strategy) Validate() error {
+	if s.Canary < 0 {
+		return fmt.Errorf("Invalid canary: %d", s.Canary)
+	}
+	if s.BatchSize < 0 {
+		return fmt.Errorf("Invalid batch size: %d", s.BatchSize)
+	}
+	if s.BatchPercent < 0 || s.BatchPercent > 100 {
+		return fmt.Errorf("Invalid batch percent: %g", s.BatchPercent)
+	}
+	if s.BatchSize > 0 && s.BatchPercent > 0 {
+		return errors.New("Only one of batch size or batch percent should be set")
+	}
+	if _, err := s.GetPause(); err != nil {
+		return err
+	}
+	if s.MaxFailurePercent != nil && (*s.MaxFailurePercent < 0 || *s.MaxFailurePercent > 100) {
+		return fmt.Errorf("Invalid max failure percent: %g", *s.MaxFailurePercent)
+	}
+	return nil
+}
+
+// GetPause parses the Pause of the strategy, zero if it's not set
+func (s Strategy) GetPause() (time.Duration, error) {
+	if s.Pause == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s.Pause)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("Invalid pause: %s", s.Pause)
+	}
+	return d, nil
+}
+
+// BatchSizes returns the number of hosts in each step of the rollout to numHosts hosts, starting with the canary hosts
+func (s Strategy) BatchSizes(numHosts int) []int {
+	var sizes []int
+
+	remaining := numHosts
+	if s.Canary > 0 && remaining > 0 {
+		n := s.Canary
+		if n > remaining {
+			n = remaining
+		}
+		sizes = append(sizes, n)
+		remaining -= n
+	}
+
+	batch := s.BatchSize
+	if batch == 0 && s.BatchPercent > 0 {
+		// Round up, so that there's at least one host in each batch
+		batch = int(math.Ceil(float64(numHosts) * s.BatchPercent / 100))
+	}
+	if batch <= 0 {
+		batch = remaining
+	}
+
+	for remaining > 0 {
+		n := batch
+		if n > remaining {
+			n = remaining
+		}
+		sizes = append(sizes, n)
+		remaining -= n
+	}
+
+	return sizes
+}
@@ -0,0 +1,62 @@
+package rpc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBatchSizes(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        Strategy
+		numHosts int
+		want     []int
+	}{
+		{"all at once", Strategy{}, 5, []int{5}},
+		{"no hosts", Strategy{Canary: 1, BatchSize: 2}, 0, nil},
+		{"canary only", Strategy{Canary: 2}, 5, []int{2, 3}},
+		{"canary and batch size", Strategy{Canary: 1, BatchSize: 2}, 6, []int{1, 2, 2, 1}},
+		{"canary more than hosts", Strategy{Canary: 10, BatchSize: 2}, 3, []int{3}},
+		{"batch size more than hosts", Strategy{BatchSize: 10}, 3, []int{3}},
+		{"batch percent rounds up", Strategy{BatchPercent: 25}, 10, []int{3, 3, 3, 1}},
+		{"small batch percent", Strategy{BatchPercent: 1}, 3, []int{1, 1, 1}},
+		{"batch percent of all hosts", Strategy{Canary: 1, BatchPercent: 50}, 5, []int{1, 3, 1}},
+		{"batch percent 100", Strategy{Canary: 1, BatchPercent: 100}, 4, []int{1, 3}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.s.BatchSizes(tt.numHosts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStrategyValidate(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		s       Strategy
+		wantErr bool
+	}{
+		{"zero", Strategy{}, false},
+		{"full", Strategy{Canary: 1, BatchPercent: 25, Pause: "30s", MaxFailurePercent: f(10)}, false},
+		{"max failure 0", Strategy{MaxFailurePercent: f(0)}, false},
+		{"max failure 100", Strategy{MaxFailurePercent: f(100)}, false},
+		{"negative canary", Strategy{Canary: -1}, true},
+		{"negative batch size", Strategy{BatchSize: -1}, true},
+		{"negative batch percent", Strategy{BatchPercent: -1}, true},
+		{"batch percent over 100", Strategy{BatchPercent: 101}, true},
+		{"batch size and percent", Strategy{BatchSize: 2, BatchPercent: 50}, true},
+		{"invalid pause", Strategy{Pause: "30"}, true},
+		{"negative pause", Strategy{Pause: "-1s"}, true},
+		{"negative max failure", Strategy{MaxFailurePercent: f(-1)}, true},
+		{"max failure over 100", Strategy{MaxFailurePercent: f(100.5)}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.s.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// NewTemplateData returns the data to render operations for a host with. It's used in templates as:
+//
+//	{{.Host.Endpoint}}    Endpoint of the host
+//	{{.Host.Label.role}}  Label of the host, set using the label command
+//	{{.Hostname}}         Hostname as reported by the host
+//	{{.Env}}              Env tag
+//	{{.Vars.name}}        Value from the vars file
+//
+// Unknown hostname, labels or vars are left out, so that templates using them fail instead of rendering empty values.
+func NewTemplateData(endpoint Endpoint, labels map[string]string, hostname, env string, vars map[string]interface{}) map[string]interface{} {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+
+	data := map[string]interface{}{
+		"Host": map[string]interface{}{
+			"Endpoint": endpoint,
+			"Label":    labels,
+		},
+		"Env":  env,
+		"Vars": vars,
+	}
+	if hostname != "" {
+		data["Hostname"] = hostname
+	}
+	return data
+}
+
+// Render returns a copy of the operation with the templates in path, check and params rendered using data
+func (o Operation) Render(data map[string]interface{}) (Operation, error) {
+	var err error
+
+	render := func(field, text string) string {
+		if err != nil || !isTemplate(text) {
+			return text
+		}
+		t, perr := parseTemplate(field, text)
+		if perr == nil {
+			var b bytes.Buffer
+			if perr = t.Execute(&b, data); perr == nil {
+				return b.String()
+			}
+		}
+		err = fmt.Errorf("Rendering %s: %s", field, perr.Error())
+		return text
+	}
+
+	o.PathArg = OperationArgument(render("path", string(o.PathArg)))
+	o.CheckArg = OperationArgument(render("check", string(o.CheckArg)))
+
+	if o.Params != nil {
+		params := make(map[string]interface{}, len(o.Params))
+		for k, v := range o.Params {
+			params[k] = renderParam("params."+k, v, render)
+		}
+		o.Params = params
+	}
+
+	return o, err
+}
+
+// renderParam renders the strings in the param value, copying lists and objects
+func renderParam(field string, v interface{}, render func(field, text string) string) interface{} {
+	switch v := v.(type) {
+	case string:
+		return render(field, v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = renderParam(field, item, render)
+		}
+		return list
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = renderParam(field+"."+k, item, render)
+		}
+		return m
+	default:
+		return v
+	}
+}
+
+// validateTemplates checks the syntax of the templates in path, check and params
+func (o Operation) validateTemplates() []FieldError {
+	var errs []FieldError
+	check := func(field, text string) string {
+		if isTemplate(text) {
+			if _, err := parseTemplate(field, text); err != nil {
+				errs = append(errs, FieldError{Field: field, Message: err.Error()})
+			}
+		}
+		return text
+	}
+
+	check("path", string(o.PathArg))
+	check("check", string(o.CheckArg))
+	for k, v := range o.Params {
+		renderParam("params."+k, v, check)
+	}
+	return errs
+}
+
+func isTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+func parseTemplate(field, text string) (*template.Template, error) {
+	t, err := template.New(field).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid template: %s", err.Error())
+	}
+	return t, nil
+}
@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := NewTemplateData("10.0.0.1:2020", map[string]string{"role": "web"}, "web1", "prod", map[string]interface{}{"port": float64(8080)})
+
+	o := Operation{
+		OpType:   "file_contains",
+		PathArg:  "/etc/{{.Host.Label.role}}.conf",
+		CheckArg: "listen {{.Vars.port}}",
+		Params: map[string]interface{}{
+			"user":  "{{.Env}}-{{.Hostname}}",
+			"list":  []interface{}{"{{.Host.Endpoint}}", float64(1)},
+			"plain": "no template",
+		},
+	}
+
+	got, err := o.Render(data)
+	if err != nil {
+		t.Fatalf("Render: %s", err.Error())
+	}
+	if got.PathArg != "/etc/web.conf" || got.CheckArg != "listen 8080" {
+		t.Errorf("got path %q check %q", got.PathArg, got.CheckArg)
+	}
+	wantParams := map[string]interface{}{
+		"user":  "prod-web1",
+		"list":  []interface{}{"10.0.0.1:2020", float64(1)},
+		"plain": "no template",
+	}
+	if !reflect.DeepEqual(got.Params, wantParams) {
+		t.Errorf("got params %v, want %v", got.Params, wantParams)
+	}
+	if o.Params["user"] != "{{.Env}}-{{.Hostname}}" {
+		t.Errorf("Render modified the original params")
+	}
+}
+
+func TestRenderMissingKey(t *testing.T) {
+	// No hostname, labels or vars
+	data := NewTemplateData("10.0.0.1:2020", nil, "", "prod", nil)
+
+	tests := []struct {
+		name  string
+		op    Operation
+		field string
+	}{
+		{"label", Operation{PathArg: "/etc/{{.Host.Label.role}}.conf"}, "path"},
+		{"var", Operation{CheckArg: "listen {{.Vars.port}}"}, "check"},
+		{"hostname", Operation{Params: map[string]interface{}{"user": "{{.Hostname}}"}}, "params.user"},
+		{"nested param", Operation{Params: map[string]interface{}{"env": []interface{}{"A={{.Vars.a}}"}}}, "params.env"},
+		{"syntax", Operation{PathArg: "{{.Env"}, "path"},
+	}
+
+	for _, tt := range tests {
+		_, err := tt.op.Render(data)
+		if err == nil {
+			t.Errorf("%s: expected error", tt.name)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), "Rendering "+tt.field+":") {
+			t.Errorf("%s: got %q, want error for %s", tt.name, err.Error(), tt.field)
+		}
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	o := Operation{
+		OpType:  "file_exists",
+		PathArg: "/etc/{{.Env",
+		Params:  map[string]interface{}{"user": "{{.Vars.user}}"},
+	}
+
+	errs := o.validateTemplates()
+	if len(errs) != 1 || errs[0].Field != "path" {
+		t.Errorf("got %v, want a single path error", errs)
+	}
+}
+
+func TestNewTemplateData(t *testing.T) {
+	data := NewTemplateData("10.0.0.1:2020", nil, "", "prod", nil)
+	if _, found := data["Hostname"]; found {
+		t.Errorf("unknown hostname should be left out")
+	}
+	if data["Env"] != "prod" {
+		t.Errorf("got env %v", data["Env"])
+	}
+
+	data = NewTemplateData("10.0.0.1:2020", nil, "web1", "prod", nil)
+	if data["Hostname"] != "web1" {
+		t.Errorf("got hostname %v", data["Hostname"])
+	}
+}